@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/config"
+	"github.com/zhex/local-bbp/internal/docker"
+	"github.com/zhex/local-bbp/internal/runner"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cleanOptions struct {
+	olderThan   time.Duration
+	keep        int
+	cachesOnly  bool
+	dryRun      bool
+	allProjects bool
+}
+
+func newCleanCmd() *cobra.Command {
+	opts := &cleanOptions{}
+	cmd := &cobra.Command{
+		Use:     "clean",
+		Aliases: []string{"prune"},
+		Short:   "Remove the leftover docker resources, run output and caches",
+		Run: func(cmd *cobra.Command, args []string) {
+			proj := cmd.Flag("project").Value.String()
+
+			c, fullPath, err := loadConfig(proj)
+			if err != nil {
+				log.Fatalf("Error loading config: %s", err)
+			}
+
+			cutoff := time.Now().Add(-opts.olderThan)
+
+			if !opts.cachesOnly {
+				project := fullPath
+				if opts.allProjects {
+					project = ""
+				}
+				ctx := context.Background()
+				resources, err := docker.ListResources(ctx, project)
+				if err != nil {
+					log.Fatalf("Error listing docker resources: %s", err)
+				}
+
+				kept, err := cleanRuns(c.OutputDir, fullPath, cutoff, opts)
+				if err != nil {
+					log.Fatalf("Error cleaning runs: %s", err)
+				}
+
+				// the runs of the other projects are in their own output dir if it is relative
+				raw, err := config.LoadConfig()
+				if err != nil {
+					log.Fatalf("Error loading config: %s", err)
+				}
+				if err := cleanResources(ctx, resources, kept, raw.OutputDir, cutoff, opts); err != nil {
+					log.Fatalf("Error cleaning docker resources: %s", err)
+				}
+			}
+
+			if err := cleanCaches(filepath.Join(c.OutputDir, "cache"), cutoff, opts); err != nil {
+				log.Fatalf("Error cleaning caches: %s", err)
+			}
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.olderThan, "older-than", 0, "Only remove the items older than the duration, e.g. 24h")
+	cmd.Flags().IntVar(&opts.keep, "keep", 0, "Keep the output and docker resources of the last N runs")
+	cmd.Flags().BoolVar(&opts.cachesOnly, "caches-only", false, "Only remove the caches")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Print what would be removed without removing it")
+	cmd.Flags().BoolVar(&opts.allProjects, "all-projects", false, "Remove the docker resources of all the projects")
+
+	return cmd
+}
+
+// cleanRuns removes the run folders of the project in the output dir, which can
+// be shared by projects, and returns the ids of the kept runs. The runs in
+// progress are always kept, with their resources.
+func cleanRuns(outputDir, project string, cutoff time.Time, opts *cleanOptions) (map[string]bool, error) {
+	kept := make(map[string]bool)
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return kept, nil
+		}
+		return nil, err
+	}
+
+	type run struct {
+		id      string
+		created time.Time
+	}
+	var runs []run
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "r-") {
+			continue
+		}
+		// runs of older versions have no result file
+		if result, err := runner.LoadResult(outputDir, entry.Name()); err == nil && result.Project != project {
+			continue
+		}
+		runs = append(runs, run{id: entry.Name(), created: getRunTime(entry)})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].created.After(runs[j].created)
+	})
+
+	for i, r := range runs {
+		if i < opts.keep || r.created.After(cutoff) {
+			kept[r.id] = true
+			continue
+		}
+		if runner.IsRunActive(outputDir, r.id) {
+			kept[r.id] = true
+			fmt.Printf("Skipped run %s in progress\n", r.id)
+			continue
+		}
+		if err := removeItem("run", r.id, opts.dryRun, func() error {
+			return os.RemoveAll(filepath.Join(outputDir, r.id))
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return kept, nil
+}
+
+// getRunTime returns the start time of the run, which is encoded in the id
+func getRunTime(entry os.DirEntry) time.Time {
+	if sec, err := strconv.ParseInt(strings.TrimPrefix(entry.Name(), "r-"), 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return time.Now()
+	}
+	return info.ModTime()
+}
+
+// cleanResources removes the docker resources of the runs that are not kept,
+// and not in progress in the output dir of their project
+func cleanResources(ctx context.Context, resources []*docker.Resource, kept map[string]bool, outputDir string, cutoff time.Time, opts *cleanOptions) error {
+	for _, res := range resources {
+		if kept[res.Run] || res.Created.After(cutoff) {
+			continue
+		}
+		if res.Run != "" && runner.IsRunActive(getOutputDir(outputDir, res.Project), res.Run) {
+			continue
+		}
+		if err := removeItem(res.Type, res.Name, opts.dryRun, func() error {
+			return docker.RemoveResource(ctx, res)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanCaches removes the cache entries, which are stored as <cache>/<hash> in the cache dir
+func cleanCaches(cacheDir string, cutoff time.Time, opts *cleanOptions) error {
+	keys, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, key := range keys {
+		if !key.IsDir() {
+			continue
+		}
+		keyDir := filepath.Join(cacheDir, key.Name())
+		hashes, err := os.ReadDir(keyDir)
+		if err != nil {
+			return err
+		}
+		for _, hash := range hashes {
+			info, err := hash.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().After(cutoff) {
+				continue
+			}
+			p := filepath.Join(keyDir, hash.Name())
+			if err := removeItem("cache", filepath.Join(key.Name(), hash.Name()), opts.dryRun, func() error {
+				return os.RemoveAll(p)
+			}); err != nil {
+				return err
+			}
+		}
+		if !opts.dryRun {
+			// only succeeds once the cache has no entries left
+			_ = os.Remove(keyDir)
+		}
+	}
+	return nil
+}
+
+func removeItem(kind, name string, dryRun bool, remove func() error) error {
+	if dryRun {
+		fmt.Printf("Would remove %s %s\n", kind, name)
+		return nil
+	}
+	if err := remove(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s %s\n", kind, name)
+	return nil
+}
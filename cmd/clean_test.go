@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zhex/local-bbp/internal/runner"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRun(t *testing.T, outputDir, id, project, status string) {
+	dir := filepath.Join(outputDir, id)
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	data := []byte(`{"id": "` + id + `", "project": "` + project + `", "status": "` + status + `"}`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "result.json"), data, 0644))
+}
+
+func TestCleanRuns(t *testing.T) {
+	dir := t.TempDir()
+	writeRun(t, dir, "r-1000", "/app", "success")
+	// crashed, pending without its process
+	writeRun(t, dir, "r-1001", "/app", "pending")
+	writeRun(t, dir, "r-1002", "/app", "pending")
+	writeRun(t, dir, "r-1003", "/other", "success")
+
+	unlock, err := runner.LockRun(dir, "r-1002")
+	assert.NoError(t, err)
+	defer unlock()
+
+	kept, err := cleanRuns(dir, "/app", time.Now(), &cleanOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"r-1002": true}, kept)
+
+	assert.NoDirExists(t, filepath.Join(dir, "r-1000"))
+	assert.NoDirExists(t, filepath.Join(dir, "r-1001"))
+	assert.DirExists(t, filepath.Join(dir, "r-1002"))
+	assert.DirExists(t, filepath.Join(dir, "r-1003"))
+}
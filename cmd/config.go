@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/zhex/local-bbp/internal/config"
+	"path/filepath"
+)
+
+// loadConfig loads the config and resolves the output dir against the project path
+func loadConfig(proj string) (*config.Config, string, error) {
+	c, err := config.LoadConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	fullPath, err := filepath.Abs(proj)
+	if err != nil {
+		return nil, "", err
+	}
+	c.OutputDir = getOutputDir(c.OutputDir, fullPath)
+	return c, fullPath, nil
+}
+
+// getOutputDir returns the output dir of the project, a relative output dir is
+// relative to the project path
+func getOutputDir(outputDir, project string) string {
+	if filepath.IsAbs(outputDir) {
+		return outputDir
+	}
+	return filepath.Join(project, outputDir)
+}
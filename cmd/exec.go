@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/runner"
+)
+
+func newExecCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exec",
+		Short: "Open a shell in the environment of a step instead of running its script",
+		Run: func(cmd *cobra.Command, args []string) {
+			name := cmd.Flag("name").Value.String()
+			step := cmd.Flag("step").Value.String()
+			vars, _ := cmd.Flags().GetStringArray("var")
+			artifactsFrom := cmd.Flag("artifacts-from").Value.String()
+			artifactsDir := cmd.Flag("artifacts-dir").Value.String()
+
+			r := newRunner(cmd)
+			if name == "" {
+				var err error
+				name, err = r.ResolvePipeline()
+				if err != nil {
+					log.Fatalf("Error resolving pipeline: %s", err)
+				}
+			}
+
+			r.Options.Interactive = true
+			r.Options.Variables = parseVariables(vars)
+			// the first step of the name or index only
+			r.Options.StepFilter = func(sr *runner.StepResult) bool {
+				return sr.Result.FindStep(step) == sr
+			}
+			if artifactsFrom != "" || artifactsDir == "" {
+				r.Options.PreviousResult = loadPreviousResult(r, name, artifactsFrom)
+			}
+			setArtifactsDir(r, artifactsDir)
+
+			r.Run(name, "")
+		},
+	}
+
+	addRunnerFlags(cmd)
+	cmd.Flags().StringP("name", "n", "", "Name of the pipeline, resolved from the current branch if empty")
+	cmd.Flags().String("step", "", "Name or index of the step to open the shell for")
+	cmd.Flags().StringArray("var", nil, "Variable of a custom pipeline in the format KEY=VALUE, can be repeated")
+	cmd.Flags().String("artifacts-from", "", "Run to take the artifacts of the earlier steps from, defaults to the latest run of the pipeline")
+	cmd.Flags().String("artifacts-dir", "", "Directory to download into the step as artifacts")
+	_ = cmd.MarkFlagRequired("step")
+
+	return cmd
+}
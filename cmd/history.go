@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/runner"
+	"os"
+	"time"
+)
+
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the past runs of the project",
+		Run: func(cmd *cobra.Command, args []string) {
+			proj := cmd.Flag("project").Value.String()
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			c, fullPath, err := loadConfig(proj)
+			if err != nil {
+				log.Fatalf("Error loading config: %s", err)
+			}
+
+			results, err := runner.ListResults(c.OutputDir)
+			if err != nil {
+				log.Fatalf("Error loading results: %s", err)
+			}
+
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.SetStyle(table.StyleLight)
+			t.AppendHeader(table.Row{"ID", "Pipeline", "Status", "Branch", "Commit", "Started", "Duration"})
+
+			count := 0
+			for _, result := range results {
+				// the output dir can be shared by projects
+				if result.Project != fullPath {
+					continue
+				}
+				if limit > 0 && count == limit {
+					break
+				}
+				count++
+				pipeline := result.EventName
+				if result.Interactive {
+					pipeline += " (exec)"
+				}
+				t.AppendRow(table.Row{
+					result.ID,
+					pipeline,
+					runner.GetColoredStatus(result.Status),
+					result.Branch,
+					shortCommit(result.Commit),
+					result.StartTime.Local().Format(time.DateTime),
+					result.GetDuration().Round(time.Millisecond).String(),
+				})
+			}
+			t.AppendFooter(table.Row{fmt.Sprintf("Total: %d", count)})
+			t.Render()
+		},
+	}
+
+	cmd.Flags().IntP("limit", "l", 20, "Maximum number of runs to list, 0 for all")
+
+	return cmd
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/common"
+	"github.com/zhex/local-bbp/internal/runner"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type logsOptions struct {
+	follow     bool
+	tail       int
+	timestamps bool
+	stripANSI  bool
+}
+
+func newLogsCmd() *cobra.Command {
+	opts := &logsOptions{}
+	cmd := &cobra.Command{
+		Use:   "logs [run-id] [step]",
+		Short: "Print the step logs of a run, the latest run by default",
+		Args:  cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			proj := cmd.Flag("project").Value.String()
+
+			c, fullPath, err := loadConfig(proj)
+			if err != nil {
+				log.Fatalf("Error loading config: %s", err)
+			}
+
+			runID, step := parseLogsArgs(c.OutputDir, args)
+
+			var result *runner.Result
+			if runID == "" {
+				result, err = loadLatestResult(c.OutputDir, fullPath, "")
+			} else {
+				result, err = runner.LoadResult(c.OutputDir, runID)
+			}
+			if err != nil {
+				log.Fatalf("Error loading result: %s", err)
+			}
+
+			var logs []*stepLog
+			for _, sr := range result.GetSteps() {
+				if step != "" && step != sr.Name && step != sr.GetIdxString() {
+					continue
+				}
+				logs = append(logs, &stepLog{sr: sr})
+			}
+			if len(logs) == 0 {
+				log.Fatalf("No step [%s] found in run %s", step, result.ID)
+			}
+
+			// prefix the lines with the step when printing the logs of several steps
+			if len(logs) > 1 {
+				for _, l := range logs {
+					l.prefix = fmt.Sprintf("[%s][%s] ", l.sr.GetIdxString(), l.sr.Name)
+				}
+			}
+
+			if err := printLogs(c.OutputDir, result, logs, opts); err != nil {
+				log.Fatalf("Error reading logs: %s", err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.follow, "follow", "f", false, "Follow the logs until the run ends")
+	cmd.Flags().IntVarP(&opts.tail, "tail", "n", 0, "Number of lines to print from the end of each log, 0 for all")
+	cmd.Flags().BoolVarP(&opts.timestamps, "timestamps", "t", false, "Print the timestamp of each line")
+	cmd.Flags().BoolVar(&opts.stripANSI, "strip-ansi", false, "Remove the color codes from the logs")
+
+	return cmd
+}
+
+// parseLogsArgs returns the run id and the step of the arguments. The run id
+// can be omitted when only the step is given, which can also start with "r-".
+func parseLogsArgs(outputDir string, args []string) (string, string) {
+	switch {
+	case len(args) == 2:
+		return args[0], args[1]
+	case len(args) == 1 && common.IsDirExists(filepath.Join(outputDir, args[0])):
+		return args[0], ""
+	case len(args) == 1:
+		return "", args[0]
+	}
+	return "", ""
+}
+
+// loadLatestResult returns the result of the latest run of the project, of the
+// pipeline if it is not empty
+func loadLatestResult(outputDir, project, pipeline string) (*runner.Result, error) {
+	results, err := runner.ListResults(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		// the shell sessions of the exec command are not runs of the pipeline
+		if result.Interactive {
+			continue
+		}
+		if result.Project == project && (pipeline == "" || result.EventName == pipeline) {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("no run found in %s", outputDir)
+}
+
+func printLogs(outputDir string, result *runner.Result, logs []*stepLog, opts *logsOptions) error {
+	for _, l := range logs {
+		if err := l.print(opts, opts.tail, !opts.follow); err != nil {
+			return err
+		}
+	}
+	if !opts.follow {
+		return nil
+	}
+
+	for {
+		time.Sleep(500 * time.Millisecond)
+
+		// the lock is released after the result is saved, or when the run crashed
+		active := runner.IsRunActive(outputDir, result.ID)
+		latest, err := runner.LoadResult(outputDir, result.ID)
+		done := !active || (err == nil && latest.Status != runner.StatusPending)
+		if done && err == nil && latest.Status == runner.StatusPending {
+			log.Warnf("Run %s ended without saving its result, it may have crashed or been killed", result.ID)
+		}
+
+		for _, l := range logs {
+			if err := l.print(opts, 0, done); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// stepLog reads the log file of the step from the last read offset
+type stepLog struct {
+	sr     *runner.StepResult
+	prefix string
+	offset int64
+	buf    []byte
+}
+
+// print prints the complete lines written since the last read, the last n
+// lines only if n is positive. The partial last line is printed when final.
+func (l *stepLog) print(opts *logsOptions, n int, final bool) error {
+	file, err := os.Open(l.sr.GetLogPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(l.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	l.offset += int64(len(data))
+	l.buf = append(l.buf, data...)
+
+	end := bytes.LastIndexByte(l.buf, '\n')
+	if final {
+		end = len(l.buf)
+	}
+	if end < 0 {
+		return nil
+	}
+	content := strings.TrimSuffix(string(l.buf[:end]), "\n")
+	l.buf = l.buf[min(end+1, len(l.buf)):]
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.Split(content, "\n")
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		ts, text := runner.ParseLogLine(line)
+		if opts.stripANSI {
+			text = common.StripANSI(text)
+		}
+		if opts.timestamps && !ts.IsZero() {
+			stamp := ts.Local().Format("2006-01-02 15:04:05.000")
+			if !opts.stripANSI {
+				stamp = common.ColorGrey(stamp)
+			}
+			text = stamp + " " + text
+		}
+		fmt.Println(l.prefix + text)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadLatestResult_SkipsExec(t *testing.T) {
+	dir := t.TempDir()
+	runs := map[string]string{
+		"r-1000": `{"id": "r-1000", "pipeline": "default", "project": "/app", "status": "failed", "startTime": "2024-05-01T10:00:00Z"}`,
+		"r-1001": `{"id": "r-1001", "pipeline": "default", "project": "/app", "status": "success", "interactive": true, "startTime": "2024-05-01T11:00:00Z"}`,
+	}
+	for id, data := range runs {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, id), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, id, "result.json"), []byte(data), 0644))
+	}
+
+	result, err := loadLatestResult(dir, "/app", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "r-1000", result.ID)
+}
+
+func TestParseLogsArgs(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "r-1000"), 0755))
+
+	runID, step := parseLogsArgs(dir, []string{"r-1000"})
+	assert.Equal(t, "r-1000", runID)
+	assert.Empty(t, step)
+
+	// a step named like a run
+	runID, step = parseLogsArgs(dir, []string{"r-build"})
+	assert.Empty(t, runID)
+	assert.Equal(t, "r-build", step)
+
+	runID, step = parseLogsArgs(dir, []string{"r-1000", "r-build"})
+	assert.Equal(t, "r-1000", runID)
+	assert.Equal(t, "r-build", step)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/runner"
+	"strings"
+)
+
+func newRerunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rerun <run-id>",
+		Short: "Run the pipeline of a past run again from the failed step",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			from := cmd.Flag("from").Value.String()
+			failed, _ := cmd.Flags().GetBool("failed")
+
+			if from != "" && failed {
+				log.Fatal("The --from and --failed flags cannot be used together")
+			}
+
+			r := newRunner(cmd)
+
+			prev, err := runner.LoadResult(r.Config.OutputDir, args[0])
+			if err != nil {
+				log.Fatalf("Error loading result of run %s: %s", args[0], err)
+			}
+			if prev.Project != r.Info.Path {
+				log.Fatalf("Run %s belongs to the project %s", prev.ID, prev.Project)
+			}
+			if prev.Interactive {
+				log.Fatalf("Run %s is a shell session of the exec command", prev.ID)
+			}
+
+			var fromIdx float32 = -1
+			if from != "" {
+				sr := prev.FindStep(from)
+				if sr == nil {
+					log.Fatalf("No step [%s] found in run %s", from, prev.ID)
+				}
+				fromIdx = sr.Index
+			} else if !failed {
+				for _, sr := range prev.GetSteps() {
+					if !isStepDone(sr) {
+						fromIdx = sr.Index
+						break
+					}
+				}
+				if fromIdx < 0 {
+					log.Fatalf("All the steps of run %s succeeded, use --from to select the steps to run again", prev.ID)
+				}
+			}
+
+			r.Options.StepFilter = func(sr *runner.StepResult) bool {
+				psr := prev.StepResults[sr.Index]
+				if psr == nil || psr.Name != sr.Name {
+					// the step is new to the pipeline
+					return true
+				}
+				if failed {
+					return !isStepDone(psr)
+				}
+				return sr.Index >= fromIdx
+			}
+			r.Options.PreviousResult = prev
+
+			// run in the same context as the previous run
+			r.Options.Variables = prev.Variables
+			r.Options.Clean = prev.Clean
+			if prev.Branch != "" {
+				r.Info.BranchName = prev.Branch
+			}
+			r.Info.Tag = prev.Tag
+			if strings.HasPrefix(prev.EventName, "pr/") {
+				r.Info.DestinationBranch = prev.TargetBranch
+			}
+			if prev.Clean {
+				r.Info.CommitID = prev.Commit
+			} else if prev.Commit != r.Info.CommitID {
+				log.Warnf("The commit has changed since run %s, the working tree is used", prev.ID)
+			}
+
+			r.Run(prev.EventName, prev.TargetBranch)
+		},
+	}
+
+	addRunnerFlags(cmd)
+	addOutputFlags(cmd)
+	cmd.Flags().String("from", "", "Name or index of the step to run again from, defaults to the first step that did not succeed")
+	cmd.Flags().Bool("failed", false, "Only run the steps that did not succeed")
+
+	return cmd
+}
+
+// isStepDone reports whether the step does not need to run again
+func isStepDone(sr *runner.StepResult) bool {
+	return sr.Status == runner.StatusSuccess || sr.Status == runner.StatusSkipped
+}
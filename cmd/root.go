@@ -19,8 +19,14 @@ func CreateRootCmd(version string) *cobra.Command {
 	rootCmd.AddCommand(
 		newListCmd(),
 		newRunCmd(),
+		newRerunCmd(),
+		newExecCmd(),
 		newValidateCmd(),
 		newIntegrationsCmd(),
+		newCleanCmd(),
+		newHistoryCmd(),
+		newShowCmd(),
+		newLogsCmd(),
 	)
 
 	return rootCmd
@@ -4,12 +4,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/zhex/local-bbp/internal/common"
-	"github.com/zhex/local-bbp/internal/config"
-	"github.com/zhex/local-bbp/internal/docker"
-	"github.com/zhex/local-bbp/internal/parser"
 	"github.com/zhex/local-bbp/internal/runner"
-	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -18,61 +13,116 @@ func newRunCmd() *cobra.Command {
 		Use:   "run",
 		Short: "Run a pipeline",
 		Run: func(cmd *cobra.Command, args []string) {
-			proj := cmd.Flag("project").Value.String()
 			name := cmd.Flag("name").Value.String()
 			targetBranch := cmd.Flag("target-branch").Value.String()
-			verbose, _ := cmd.Flags().GetBool("verbose")
+			clean, _ := cmd.Flags().GetBool("clean")
+			ref := cmd.Flag("ref").Value.String()
+			uncommittedChanges, _ := cmd.Flags().GetBool("uncommitted-changes")
+			branch := cmd.Flag("branch").Value.String()
+			tag := cmd.Flag("tag").Value.String()
+			pr := cmd.Flag("pr").Value.String()
+			vars, _ := cmd.Flags().GetStringArray("var")
+			only, _ := cmd.Flags().GetStringArray("only")
+			skip, _ := cmd.Flags().GetStringArray("skip")
+			from := cmd.Flag("from").Value.String()
+			until := cmd.Flag("until").Value.String()
+			artifactsFrom := cmd.Flag("artifacts-from").Value.String()
+			artifactsDir := cmd.Flag("artifacts-dir").Value.String()
+			debugOnFailure, _ := cmd.Flags().GetBool("debug-on-failure")
+			tui, _ := cmd.Flags().GetBool("tui")
 
-			if verbose {
-				log.SetLevel(log.DebugLevel)
+			if tag != "" && pr != "" {
+				log.Fatal("The --tag and --pr flags cannot be used together")
 			}
 
-			if !strings.HasPrefix(name, "pr/") {
-				targetBranch = ""
+			if debugOnFailure && tui {
+				log.Fatal("The --debug-on-failure and --tui flags cannot be used together")
 			}
 
-			var secrets map[string]string
-
-			secretFile := cmd.Flag("secrets-file").Value.String()
-			if secretFile != "" {
-				data, err := os.ReadFile(secretFile)
+			r := newRunner(cmd)
+			r.Options.Clean = clean || ref != ""
+			r.Options.UncommittedChanges = uncommittedChanges
+			r.Options.DebugOnFailure = debugOnFailure
+			r.Options.Variables = parseVariables(vars)
+			if ref != "" {
+				commit, err := common.ResolveGitRef(r.Info.Path, ref)
 				if err != nil {
-					log.Fatalf("Error reading secrets file: %s", err)
+					log.Fatalf("Error resolving git ref: %s", err)
 				}
-				secrets = parser.ParseSecrets(data)
+				r.Info.CommitID = commit
 			}
 
-			c, err := config.LoadConfig()
-			if err != nil {
-				log.Fatalf("Error loading config: %s", err)
+			if branch != "" {
+				r.Info.BranchName = branch
 			}
-
-			arch := common.GetArch()
-			if !common.Contains(docker.SupportedArchitectures, arch) {
-				log.Fatalf("Unsupported architecture: %s", arch)
+			r.Info.Tag = tag
+			if pr != "" {
+				source, destination, _ := strings.Cut(pr, ":")
+				if source != "" {
+					r.Info.BranchName = source
+				}
+				if destination != "" {
+					targetBranch = destination
+				}
+				r.Info.DestinationBranch = targetBranch
+			}
+			if name == "" {
+				var err error
+				name, err = r.ResolvePipeline()
+				if err != nil {
+					log.Fatalf("Error resolving pipeline: %s", err)
+				}
+			}
+			if strings.HasPrefix(name, "pr/") {
+				r.Info.DestinationBranch = targetBranch
+			} else {
+				targetBranch = ""
+				r.Info.DestinationBranch = ""
 			}
 
-			dockerPath := filepath.Join(c.ToolDir, arch, "docker/docker")
-			if !common.IsFileExists(dockerPath) {
-				log.Info("Downloading linux docker cli binary")
-				if err = docker.DownloadDockerCliBinary(c.DockerVersion, c.ToolDir); err != nil {
-					log.Fatalf("Error downloading docker cli binary: %s", err)
+			selection := &runner.StepSelection{Only: only, Skip: skip, From: from, Until: until}
+			if !selection.IsEmpty() {
+				r.Options.StepFilter = selection.Match
+				if artifactsFrom != "" || artifactsDir == "" {
+					r.Options.PreviousResult = loadPreviousResult(r, name, artifactsFrom)
 				}
 			}
+			setArtifactsDir(r, artifactsDir)
 
-			fullPath, _ := filepath.Abs(proj)
-			if !filepath.IsAbs(c.OutputDir) {
-				c.OutputDir = filepath.Join(fullPath, c.OutputDir)
+			if debugOnFailure && r.Options.Output == runner.OutputStream {
+				if r.Plan == nil {
+					if err := r.LoadPlan(); err != nil {
+						log.Fatalf("Error loading plan: %s", err)
+					}
+				}
+				// the output of the other steps would mess up the terminal of the shell
+				if r.Plan.HasParallelSteps(name) {
+					log.Fatal("The --debug-on-failure flag cannot be used with parallel steps in the stream output, use --output file")
+				}
 			}
-			r := runner.New(fullPath, c, secrets)
+
 			r.Run(name, targetBranch)
 		},
 	}
 
-	cmd.Flags().StringP("name", "n", "default", "Name of the workflow to run")
-	cmd.Flags().StringP("secrets-file", "s", "", "Path to the secrets file")
-	cmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging")
+	cmd.Flags().StringP("name", "n", "", "Name of the pipeline to run, resolved from the branch, tag or pull request if empty")
+	addRunnerFlags(cmd)
+	addOutputFlags(cmd)
 	cmd.Flags().StringP("target-branch", "t", "main", "Target branch for a pull request pipeline. Default is 'main'")
+	cmd.Flags().Bool("clean", false, "Only copy the committed files of HEAD into the build container")
+	cmd.Flags().String("ref", "", "Git ref to check out in the build container, implies --clean")
+	cmd.Flags().String("branch", "", "Branch to run the pipeline for, defaults to the current branch")
+	cmd.Flags().String("tag", "", "Tag to run the pipeline for")
+	cmd.Flags().String("pr", "", "Pull request to run the pipeline for, in the format <source>:<destination>")
+	cmd.Flags().StringArray("var", nil, "Variable of a custom pipeline in the format KEY=VALUE, can be repeated")
+	cmd.Flags().StringArray("only", nil, "Only run the steps matching the name glob or index, can be repeated")
+	cmd.Flags().StringArray("skip", nil, "Skip the steps matching the name glob or index, can be repeated")
+	cmd.Flags().String("from", "", "Start the pipeline from the step matching the name glob or index")
+	cmd.Flags().String("until", "", "Stop the pipeline after the step matching the name glob or index")
+	cmd.Flags().String("artifacts-from", "", "Run to take the artifacts of the skipped steps from, defaults to the latest run of the pipeline")
+	cmd.Flags().String("artifacts-dir", "", "Directory to download into the steps as artifacts")
+	cmd.Flags().Bool("debug-on-failure", false, "Open a shell in the build container when the script of a step fails")
+	cmd.Flags().Bool("uncommitted-changes", true, "Include uncommitted changes when matching the changesets conditions")
 
 	return cmd
 }
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/common"
+	"github.com/zhex/local-bbp/internal/docker"
+	"github.com/zhex/local-bbp/internal/parser"
+	"github.com/zhex/local-bbp/internal/runner"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// addRunnerFlags adds the flags shared by the commands that run pipelines
+func addRunnerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("secrets-file", "s", "", "Path to the secrets file")
+	cmd.Flags().BoolP("verbose", "v", false, "Enable verbose logging")
+}
+
+// addOutputFlags adds the flags of the output and the manual steps
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", runner.OutputStream, "Output mode of the step scripts: stream, file or quiet")
+	cmd.Flags().Bool("tui", false, "Show a terminal dashboard of the steps instead of the plain output")
+	cmd.Flags().Bool("auto-approve", false, "Run manual steps without asking for approval")
+	cmd.Flags().Bool("stop-at-manual", false, "Pause the pipeline at the first manual step")
+	cmd.Flags().StringArray("report", nil, "Report to write at the end of the run: json, junit or markdown, with an optional =<path>. Can be repeated")
+}
+
+// newRunner creates the runner of the project from the flags added by addRunnerFlags,
+// and by addOutputFlags if the command has them
+func newRunner(cmd *cobra.Command) *runner.Runner {
+	proj := cmd.Flag("project").Value.String()
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+	stopAtManual, _ := cmd.Flags().GetBool("stop-at-manual")
+	tui, _ := cmd.Flags().GetBool("tui")
+	reports, _ := cmd.Flags().GetStringArray("report")
+	output := runner.OutputStream
+	if f := cmd.Flag("output"); f != nil {
+		output = f.Value.String()
+	}
+
+	if verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	if autoApprove && stopAtManual {
+		log.Fatal("The --auto-approve and --stop-at-manual flags cannot be used together")
+	}
+
+	if !common.Contains(runner.OutputModes, output) {
+		log.Fatalf("Invalid output mode: %s, expect one of %s", output, strings.Join(runner.OutputModes, ", "))
+	}
+
+	var reportTargets []*runner.ReportTarget
+	for _, s := range reports {
+		target, err := runner.ParseReportTarget(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if target.Path != "" {
+			target.Path, _ = filepath.Abs(target.Path)
+		}
+		reportTargets = append(reportTargets, target)
+	}
+
+	var secrets map[string]string
+
+	secretFile := cmd.Flag("secrets-file").Value.String()
+	if secretFile != "" {
+		data, err := os.ReadFile(secretFile)
+		if err != nil {
+			log.Fatalf("Error reading secrets file: %s", err)
+		}
+		secrets = parser.ParseSecrets(data)
+	}
+
+	c, fullPath, err := loadConfig(proj)
+	if err != nil {
+		log.Fatalf("Error loading config: %s", err)
+	}
+
+	arch := common.GetArch()
+	if !common.Contains(docker.SupportedArchitectures, arch) {
+		log.Fatalf("Unsupported architecture: %s", arch)
+	}
+
+	dockerPath := filepath.Join(c.ToolDir, arch, "docker/docker")
+	if !common.IsFileExists(dockerPath) {
+		log.Info("Downloading linux docker cli binary")
+		if err = docker.DownloadDockerCliBinary(c.DockerVersion, c.ToolDir); err != nil {
+			log.Fatalf("Error downloading docker cli binary: %s", err)
+		}
+	}
+
+	r := runner.New(fullPath, c, secrets)
+	r.Options.Output = output
+	r.Options.TUI = tui
+	r.Options.AutoApprove = autoApprove
+	r.Options.StopAtManual = stopAtManual
+	r.Options.Reports = reportTargets
+	return r
+}
+
+// parseVariables parses the variables of the --var flags in the format KEY=VALUE
+func parseVariables(vars []string) map[string]string {
+	variables := make(map[string]string)
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			log.Fatalf("Invalid variable, expect KEY=VALUE: %s", v)
+		}
+		variables[key] = value
+	}
+	return variables
+}
+
+// loadPreviousResult loads the run that the artifacts of the skipped steps are taken
+// from, which is the latest run of the pipeline if id is empty
+func loadPreviousResult(r *runner.Runner, name, id string) *runner.Result {
+	if id != "" {
+		result, err := runner.LoadResult(r.Config.OutputDir, id)
+		if err != nil {
+			log.Fatalf("Error loading result of run %s: %s", id, err)
+		}
+		return result
+	}
+	result, err := loadLatestResult(r.Config.OutputDir, r.Info.Path, name)
+	if err != nil {
+		return nil
+	}
+	log.Infof("Using the artifacts of run %s for the skipped steps", result.ID)
+	return result
+}
+
+// setArtifactsDir sets the artifacts directory of the --artifacts-dir flag
+func setArtifactsDir(r *runner.Runner, dir string) {
+	if dir == "" {
+		return
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil || !common.IsDirExists(abs) {
+		log.Fatalf("Artifacts directory not found: %s", dir)
+	}
+	r.Options.ArtifactsDir = abs
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/zhex/local-bbp/internal/runner"
+	"os"
+	"strconv"
+	"time"
+)
+
+func newShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show the steps of a past run",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			proj := cmd.Flag("project").Value.String()
+
+			c, _, err := loadConfig(proj)
+			if err != nil {
+				log.Fatalf("Error loading config: %s", err)
+			}
+
+			result, err := runner.LoadResult(c.OutputDir, args[0])
+			if err != nil {
+				log.Fatalf("Error loading result of run %s: %s", args[0], err)
+			}
+
+			fmt.Printf("Run:      %s\n", result.ID)
+			fmt.Printf("Pipeline: %s\n", result.EventName)
+			fmt.Printf("Status:   %s\n", runner.GetColoredStatus(result.Status))
+			fmt.Printf("Branch:   %s (%s)\n", result.Branch, shortCommit(result.Commit))
+			fmt.Printf("Started:  %s\n", result.StartTime.Local().Format(time.DateTime))
+			fmt.Printf("Duration: %s\n", result.GetDuration().Round(time.Millisecond))
+			fmt.Printf("Output:   %s\n", result.GetResultPath())
+			if result.PreviousRun != "" {
+				fmt.Printf("Previous: %s\n", result.PreviousRun)
+			}
+
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.SetStyle(table.StyleLight)
+			t.AppendHeader(table.Row{"Step", "Status", "Duration", "Image", "Exit Code", "Tests"})
+
+			lastGroup := -1
+			for _, sr := range result.GetSteps() {
+				name := fmt.Sprintf("%s %s", sr.GetIdxString(), sr.Name)
+				if sr.Group != "" {
+					// print the group once above its steps
+					if group := int(sr.Index); group != lastGroup {
+						lastGroup = group
+						t.AppendRow(table.Row{fmt.Sprintf("%d %s", group, sr.Group)})
+					}
+					name = "└ " + name
+				}
+				exitCode := ""
+				if sr.Status == runner.StatusFailed {
+					exitCode = strconv.Itoa(sr.ExitCode)
+				}
+				tests := ""
+				if sr.Tests != nil {
+					tests = fmt.Sprintf("%d passed, %d failed, %d skipped", sr.Tests.Passed, sr.Tests.Failed, sr.Tests.Skipped)
+				}
+				t.AppendRow(table.Row{
+					name,
+					runner.GetColoredStatus(sr.Status),
+					sr.GetDuration().Round(time.Millisecond).String(),
+					sr.Image,
+					exitCode,
+					tests,
+				})
+			}
+			t.Render()
+
+			for _, sr := range result.GetSteps() {
+				if msg := sr.GetFailedCommandMessage(); msg != "" {
+					fmt.Printf("\n%s\n", msg)
+				}
+			}
+
+			for _, sr := range result.GetSteps() {
+				if sr.Tests == nil || len(sr.Tests.Failures) == 0 {
+					continue
+				}
+				fmt.Printf("\nFailed tests of step [%s] %s:\n", sr.GetIdxString(), sr.Name)
+				for _, failure := range sr.Tests.Failures {
+					fmt.Printf("  %s: %s\n", failure.FullName(), failure.Message)
+				}
+			}
+		},
+	}
+
+	return cmd
+}
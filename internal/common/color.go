@@ -1,8 +1,19 @@
 package common
 
-import "github.com/fatih/color"
+import (
+	"github.com/fatih/color"
+	"regexp"
+)
 
 var ColorGrey = color.New(color.FgHiBlack).SprintFunc()
 var ColorGreen = color.New(color.FgGreen).SprintFunc()
 var ColorRed = color.New(color.FgRed).SprintFunc()
 var ColorCyan = color.New(color.FgCyan).SprintFunc()
+var ColorYellow = color.New(color.FgYellow).SprintFunc()
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]|\x1b\][^\x07]*\x07`)
+
+// StripANSI removes the ANSI escape sequences, e.g. colors, from the text
+func StripANSI(text string) string {
+	return ansiPattern.ReplaceAllString(text, "")
+}
@@ -0,0 +1,11 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	assert.Equal(t, "hello world", StripANSI("\x1b[31mhello\x1b[0m world"))
+	assert.Equal(t, "plain", StripANSI("plain"))
+}
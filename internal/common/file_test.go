@@ -2,6 +2,8 @@ package common
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -26,3 +28,42 @@ func TestGetFilesSha256(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "", sha256)
 }
+
+func TestReadLastLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	_ = os.WriteFile(file, []byte("line1\r\nline2\nline3\n"), 0644)
+
+	lines, err := ReadLastLines(file, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"line2", "line3"}, lines)
+
+	lines, err = ReadLastLines(file, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"line1", "line2", "line3"}, lines)
+
+	_, err = ReadLastLines("testdata/file_unknown.txt", 1)
+	assert.Error(t, err)
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	assert.Equal(t, "1-build and test.log", SanitizeFileName("1-build and test.log"))
+	assert.Equal(t, "2-deploy_prod_eu.log", SanitizeFileName("2-deploy/prod:eu.log"))
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(src, "dist", "js"), 0755)
+	_ = os.WriteFile(filepath.Join(src, "dist", "js", "app.js"), []byte("app"), 0644)
+	_ = os.Symlink("js/app.js", filepath.Join(src, "dist", "main.js"))
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	assert.NoError(t, CopyDir(src, dst))
+
+	data, err := os.ReadFile(filepath.Join(dst, "dist", "js", "app.js"))
+	assert.NoError(t, err)
+	assert.Equal(t, "app", string(data))
+
+	link, err := os.Readlink(filepath.Join(dst, "dist", "main.js"))
+	assert.NoError(t, err)
+	assert.Equal(t, "js/app.js", link)
+}
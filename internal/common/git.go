@@ -1,7 +1,9 @@
 package common
 
 import (
+	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -35,42 +37,130 @@ func GetGitOwner(path string) (string, error) {
 	return trimOutput(out), nil
 }
 
-func GetGitChangedFiles(path string, branch string) ([]string, error) {
-	cmd := exec.Command("git", "diff", "--name-only")
-	cmd.Dir = path
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
+// GetGitChangedFiles returns the files changed by the commit, the same way as Bitbucket does.
+// For pull requests, all changes since the merge base with the target branch are included,
+// otherwise only the changes of the commit itself. Uncommitted changes and untracked files
+// are added if includeWorkingTree is set.
+func GetGitChangedFiles(path, commit, targetBranch string, includeWorkingTree bool) ([]string, error) {
+	var outputs []string
+	if targetBranch != "" {
+		base, err := gitOutput(path, "merge-base", commit, targetBranch)
+		if err != nil {
+			return nil, err
+		}
+		out, err := gitOutput(path, "diff", "--name-only", base, commit)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	} else {
+		out, err := gitOutput(path, "diff-tree", "--no-commit-id", "--name-only", "-r", "--root", "-m", "--first-parent", commit)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
 	}
-	uncommittedChanges := strings.Split(trimOutput(out), "\n")
 
-	if branch == "" {
-		branch = "HEAD~1"
+	if includeWorkingTree {
+		out, err := gitOutput(path, "diff", "--name-only", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+		out, err = gitOutput(path, "ls-files", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
 	}
 
-	cmd2 := exec.Command("git", "diff", "--name-only", "HEAD", branch)
-	cmd2.Dir = path
-	out2, err := cmd2.Output()
-	if err != nil {
-		return nil, err
+	changes := make(map[string]bool)
+	files := make([]string, 0)
+	for _, out := range outputs {
+		for _, file := range strings.Split(out, "\n") {
+			if file != "" && !changes[file] {
+				changes[file] = true
+				files = append(files, file)
+			}
+		}
 	}
-	lastCommitChanges := strings.Split(trimOutput(out2), "\n")
+	return files, nil
+}
 
-	changes := make(map[string]bool)
-	for _, file := range uncommittedChanges {
-		changes[file] = true
+// CreateGitRepo creates a git repository in dst which only holds the metadata
+// of the commit fetched from the src repository, with the given depth of history.
+// The commit is checked out as the branch, or as a detached HEAD if the branch is empty.
+func CreateGitRepo(src, dst, branch, commit string, depth int) error {
+	if err := runGit("", "init", "-q", dst); err != nil {
+		return err
 	}
-	for _, file := range lastCommitChanges {
-		changes[file] = true
+	args := []string{"-c", "protocol.version=2", "fetch", "-q", "--no-tags"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
 	}
-	var getKeys = func(m map[string]bool) []string {
-		keys := make([]string, 0, len(m))
-		for k := range m {
-			keys = append(keys, k)
+	args = append(args, "file://"+src, commit)
+	if err := runGit(dst, args...); err != nil {
+		return err
+	}
+	if branch == "" || branch == "HEAD" {
+		if err := runGit(dst, "update-ref", "--no-deref", "HEAD", commit); err != nil {
+			return err
+		}
+	} else {
+		if err := runGit(dst, "update-ref", "refs/heads/"+branch, commit); err != nil {
+			return err
 		}
-		return keys
+		if err := runGit(dst, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+			return err
+		}
+	}
+	// populate the index without checking out the files
+	return runGit(dst, "reset", "-q")
+}
+
+// CheckoutGitRepo restores the files of the checked out commit in the repo
+func CheckoutGitRepo(repo string) error {
+	return runGit(repo, "reset", "-q", "--hard")
+}
+
+// ResolveGitRef returns the commit id that the ref points to
+func ResolveGitRef(path, ref string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown git ref: %s", ref)
+	}
+	return trimOutput(out), nil
+}
+
+func SetGitConfig(repo, key, value string) error {
+	return runGit(repo, "config", key, value)
+}
+
+// FetchGitLFS downloads the LFS objects of the commit from the src repository into repo
+func FetchGitLFS(repo, src, commit string) error {
+	return runGit(repo, "lfs", "fetch", "file://"+src, commit)
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return trimOutput(out), nil
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, trimOutput(out))
 	}
-	return getKeys(changes), nil
+	return nil
 }
 
 func trimOutput(data []byte) string {
@@ -0,0 +1,107 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestGit(t *testing.T) func(dir string, args ...string) string {
+	return func(dir string, args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@test"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		assert.NoError(t, err)
+		return trimOutput(out)
+	}
+}
+
+func commitTestFile(t *testing.T, dir, name, content string) {
+	git := newTestGit(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	git(dir, "add", name)
+	git(dir, "commit", "-q", "-m", content)
+}
+
+func TestCreateGitRepo(t *testing.T) {
+	src := t.TempDir()
+	git := newTestGit(t)
+	git(src, "init", "-q")
+	for _, content := range []string{"1", "2", "3"} {
+		commitTestFile(t, src, "file.txt", content)
+	}
+	commit, err := GetGitCommit(src)
+	assert.NoError(t, err)
+
+	dst := filepath.Join(t.TempDir(), "repo")
+	err = CreateGitRepo(src, dst, "feature", commit, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(strings.Split(git(dst, "log", "--oneline"), "\n")))
+	assert.Equal(t, "feature", git(dst, "rev-parse", "--abbrev-ref", "HEAD"))
+	assert.Equal(t, commit, git(dst, "rev-parse", "HEAD"))
+
+	dst = filepath.Join(t.TempDir(), "repo")
+	err = CreateGitRepo(src, dst, "", commit, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(strings.Split(git(dst, "log", "--oneline"), "\n")))
+	assert.Equal(t, "HEAD", git(dst, "rev-parse", "--abbrev-ref", "HEAD"))
+
+	err = CheckoutGitRepo(dst)
+	assert.NoError(t, err)
+	data, _ := os.ReadFile(filepath.Join(dst, "file.txt"))
+	assert.Equal(t, "3", string(data))
+}
+
+func TestResolveGitRef(t *testing.T) {
+	src := t.TempDir()
+	git := newTestGit(t)
+	git(src, "init", "-q")
+	commitTestFile(t, src, "file.txt", "1")
+	git(src, "tag", "v1.0.0")
+	first, _ := GetGitCommit(src)
+	commitTestFile(t, src, "file.txt", "2")
+	head, _ := GetGitCommit(src)
+
+	commit, err := ResolveGitRef(src, "HEAD")
+	assert.NoError(t, err)
+	assert.Equal(t, head, commit)
+
+	commit, err = ResolveGitRef(src, "v1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, first, commit)
+
+	_, err = ResolveGitRef(src, "unknown-ref-for-test")
+	assert.Error(t, err)
+}
+
+func TestGetGitChangedFiles(t *testing.T) {
+	src := t.TempDir()
+	git := newTestGit(t)
+	git(src, "init", "-q", "-b", "main")
+	commitTestFile(t, src, "main.txt", "1")
+	git(src, "checkout", "-q", "-b", "feature")
+	commitTestFile(t, src, "a.txt", "2")
+	commitTestFile(t, src, "b.txt", "3")
+	git(src, "checkout", "-q", "main")
+	commitTestFile(t, src, "main.txt", "4")
+	git(src, "checkout", "-q", "feature")
+
+	commit, _ := GetGitCommit(src)
+
+	files, err := GetGitChangedFiles(src, commit, "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b.txt"}, files)
+
+	files, err = GetGitChangedFiles(src, commit, "main", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b.txt"}, files)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("changed"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "new.txt"), []byte("new"), 0644))
+	files, err = GetGitChangedFiles(src, commit, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b.txt", "a.txt", "new.txt"}, files)
+}
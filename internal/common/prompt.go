@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var promptLock sync.Mutex
+
+// Prompt prints the question and reads a single line answer from stdin until
+// the context is done. Concurrent prompts are serialized so parallel steps do
+// not interleave.
+func Prompt(ctx context.Context, question string) (string, error) {
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	fmt.Print(question)
+	input := NewCancelableReader(os.Stdin)
+	stop := context.AfterFunc(ctx, input.Cancel)
+	defer stop()
+
+	// the answer is read a byte at a time, to leave the next lines to the next prompt
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if ctx.Err() != nil {
+			fmt.Println()
+			return "", ctx.Err()
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(line)), nil
+}
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPrompt(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	assert.NoError(t, err)
+	defer pr.Close()
+	defer pw.Close()
+
+	stdin := os.Stdin
+	os.Stdin = pr
+	defer func() { os.Stdin = stdin }()
+
+	_, _ = pw.Write([]byte(" yes \nskip\n"))
+	answer, err := Prompt(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "yes", answer)
+	answer, err = Prompt(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "skip", answer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = Prompt(ctx, "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"sync"
+)
+
+// pollTimeout is how often a CancelableReader checks if it is cancelled, in milliseconds
+const pollTimeout = 100
+
+// CancelableReader reads a file, e.g. stdin, until it is cancelled. It only
+// reads when the file has input, so no read is left blocked on the file to
+// take the input of the next reader once the reader is cancelled.
+type CancelableReader struct {
+	file *os.File
+	done chan struct{}
+	once sync.Once
+}
+
+func NewCancelableReader(file *os.File) *CancelableReader {
+	return &CancelableReader{
+		file: file,
+		done: make(chan struct{}),
+	}
+}
+
+// Read returns io.EOF once the reader is cancelled
+func (r *CancelableReader) Read(p []byte) (int, error) {
+	fds := []unix.PollFd{{Fd: int32(r.file.Fd()), Events: unix.POLLIN}}
+	for {
+		select {
+		case <-r.done:
+			return 0, io.EOF
+		default:
+		}
+		n, err := unix.Poll(fds, pollTimeout)
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+		select {
+		case <-r.done:
+			return 0, io.EOF
+		default:
+		}
+		return r.file.Read(p)
+	}
+}
+
+func (r *CancelableReader) Cancel() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCancelableReader(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	assert.NoError(t, err)
+	defer pr.Close()
+	defer pw.Close()
+
+	r := NewCancelableReader(pr)
+	_, _ = pw.Write([]byte("y\n"))
+	buf := make([]byte, 8)
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "y\n", string(buf[:n]))
+
+	done := make(chan error)
+	go func() {
+		_, err := r.Read(buf)
+		done <- err
+	}()
+	r.Cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, io.EOF, err)
+	case <-time.After(time.Second):
+		t.Fatal("read is not cancelled")
+	}
+
+	// the input after the cancel is left to the next reader
+	_, _ = pw.Write([]byte("n\n"))
+	n, err = pr.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "n\n", string(buf[:n]))
+}
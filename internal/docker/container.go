@@ -13,7 +13,9 @@ import (
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/archive"
+	"github.com/moby/term"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zhex/local-bbp/internal/common"
 	"io"
 	"os"
 	"path"
@@ -50,7 +52,8 @@ func (c *Container) IsImageExists(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
-func (c *Container) Pull(ctx context.Context) error {
+// Pull pulls the image of the container and writes the progress to out
+func (c *Container) Pull(ctx context.Context, out io.Writer) error {
 	reader, err := c.client.ImagePull(ctx, c.Inputs.Image.Name, image.PullOptions{
 		RegistryAuth: c.getAuthString(),
 	})
@@ -58,7 +61,7 @@ func (c *Container) Pull(ctx context.Context) error {
 		return err
 	}
 	defer reader.Close()
-	_, err = io.Copy(os.Stdout, reader)
+	_, err = io.Copy(out, reader)
 	return err
 }
 
@@ -68,13 +71,36 @@ func (c *Container) Create(ctx context.Context, net *Network, requireVol bool, m
 		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
 	}
 	conf := &container.Config{
-		Image: c.Inputs.Image.Name,
-		Tty:   true,
-		Env:   envs,
-		User:  fmt.Sprintf("%d", c.Inputs.Image.RunAsUser),
+		Image:  c.Inputs.Image.Name,
+		Tty:    true,
+		Env:    envs,
+		User:   fmt.Sprintf("%d", c.Inputs.Image.RunAsUser),
+		Labels: c.Inputs.Labels,
 	}
 
+	// the volumes are removed with the container by Destroy, so they are
+	// removed here if the container is not created
+	var volumes []string
+	defer func() {
+		if c.ID != "" {
+			return
+		}
+		for _, name := range volumes {
+			_ = c.client.VolumeRemove(context.WithoutCancel(ctx), name, true)
+		}
+	}()
+
 	if c.DockerDaemonVol != nil {
+		// create the volume up front, docker would create it implicitly without labels
+		v, err := c.client.VolumeCreate(ctx, volume.CreateOptions{
+			Name:   c.DockerDaemonVol.Name,
+			Labels: c.Inputs.Labels,
+		})
+		if err != nil {
+			return err
+		}
+		c.DockerDaemonVol = &v
+		volumes = append(volumes, v.Name)
 
 		conf.Healthcheck = &container.HealthConfig{
 			Test:        []string{"CMD", "test", "-e", "/var/run/docker.sock"},
@@ -89,12 +115,14 @@ func (c *Container) Create(ctx context.Context, net *Network, requireVol bool, m
 
 	if requireVol {
 		v, err := c.client.VolumeCreate(ctx, volume.CreateOptions{
-			Name: fmt.Sprintf("vol_%s", c.Inputs.Name),
+			Name:   fmt.Sprintf("vol_%s", c.Inputs.Name),
+			Labels: c.Inputs.Labels,
 		})
 		if err != nil {
 			return err
 		}
 		c.Vol = &v
+		volumes = append(volumes, v.Name)
 
 		mounts = append(mounts, mount.Mount{
 			Source: c.Vol.Name,
@@ -108,6 +136,13 @@ func (c *Container) Create(ctx context.Context, net *Network, requireVol bool, m
 		NetworkMode: container.NetworkMode(net.Name),
 		Privileged:  true,
 	}
+	if c.Inputs.Memory > 0 {
+		memory := int64(c.Inputs.Memory) * 1024 * 1024
+		hostConf.Resources = container.Resources{
+			Memory:     memory,
+			MemorySwap: memory,
+		}
+	}
 	plat := &v1.Platform{}
 	networkConf := &network.NetworkingConfig{
 		EndpointsConfig: map[string]*network.EndpointSettings{
@@ -146,9 +181,29 @@ func (c *Container) Start(ctx context.Context) error {
 	return nil
 }
 
+func (c *Container) IsOOMKilled(ctx context.Context) (bool, error) {
+	inspector, err := c.client.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return false, err
+	}
+	return inspector.State.OOMKilled, nil
+}
+
 func (c *Container) Exec(ctx context.Context, workdir string, cmd []string, outputHandler func(reader io.Reader) error) error {
+	return c.ExecWithEnv(ctx, workdir, cmd, nil, outputHandler)
+}
+
+// ExecWithEnv runs the command in the container with the extra environment variables,
+// an *ExitError is returned if the command exits with a non-zero code. The output
+// handler has returned when ExecWithEnv returns, even if the context is done.
+func (c *Container) ExecWithEnv(ctx context.Context, workdir string, cmd []string, envs map[string]string, outputHandler func(reader io.Reader) error) error {
+	var env []string
+	for k, v := range envs {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
 	exec, err := c.client.ContainerExecCreate(ctx, c.ID, container.ExecOptions{
 		Cmd:          cmd,
+		Env:          env,
 		AttachStdout: true,
 		AttachStderr: true,
 		Tty:          true,
@@ -157,6 +212,7 @@ func (c *Container) Exec(ctx context.Context, workdir string, cmd []string, outp
 	if err != nil {
 		return err
 	}
+
 	resp, err := c.client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{
 		Tty: true,
 	})
@@ -167,24 +223,29 @@ func (c *Container) Exec(ctx context.Context, workdir string, cmd []string, outp
 
 	done := make(chan int, 1)
 	errChan := make(chan error, 1)
+	handled := make(chan struct{})
 
 	go func() {
+		defer close(handled)
 		if outputHandler != nil {
 			if err := outputHandler(resp.Reader); err != nil {
 				errChan <- err
+				return
 			}
 		}
 		for {
 			inspectResp, err := c.client.ContainerExecInspect(ctx, exec.ID)
 			if err != nil {
 				errChan <- err
+				return
 			}
 			if !inspectResp.Running {
 				if inspectResp.ExitCode == 0 {
 					done <- 0
 				} else {
-					errChan <- fmt.Errorf("exitcode '%d': failure", inspectResp.ExitCode)
+					errChan <- &ExitError{Code: inspectResp.ExitCode}
 				}
+				return
 			}
 			time.Sleep(100 * time.Millisecond)
 		}
@@ -192,6 +253,10 @@ func (c *Container) Exec(ctx context.Context, workdir string, cmd []string, outp
 
 	select {
 	case <-ctx.Done():
+		// the output handler returns once the output is closed, so the caller
+		// does not share the state of the handler with it
+		resp.Close()
+		<-handled
 		return ctx.Err()
 	case <-done:
 		return nil
@@ -200,6 +265,77 @@ func (c *Container) Exec(ctx context.Context, workdir string, cmd []string, outp
 	}
 }
 
+// ExecInteractive runs the command in the container with a TTY attached to in and out,
+// the terminal of in is set to raw mode until the command exits
+func (c *Container) ExecInteractive(ctx context.Context, workdir string, cmd []string, envs map[string]string, in *os.File, out io.Writer) error {
+	var env []string
+	for k, v := range envs {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	exec, err := c.client.ContainerExecCreate(ctx, c.ID, container.ExecOptions{
+		Cmd:          cmd,
+		Env:          env,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+		WorkingDir:   workdir,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{
+		Tty: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	if fd, isTerminal := term.GetFdInfo(in); isTerminal {
+		state, err := term.SetRawTerminal(fd)
+		if err != nil {
+			return err
+		}
+		defer term.RestoreTerminal(fd, state)
+
+		if size, err := term.GetWinsize(fd); err == nil {
+			_ = c.client.ContainerExecResize(ctx, exec.ID, container.ResizeOptions{
+				Height: uint(size.Height),
+				Width:  uint(size.Width),
+			})
+		}
+	}
+
+	// the input is only read while the command runs, so no read is left on in
+	// to take the input of the next reader
+	input := common.NewCancelableReader(in)
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		_, _ = io.Copy(resp.Conn, input)
+		_ = resp.CloseWrite()
+	}()
+
+	// the output ends when the command exits
+	_, err = io.Copy(out, resp.Reader)
+	input.Cancel()
+	<-inputDone
+	if err != nil {
+		return err
+	}
+
+	inspectResp, err := c.client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if inspectResp.ExitCode != 0 {
+		return &ExitError{Code: inspectResp.ExitCode}
+	}
+	return nil
+}
+
 func (c *Container) Destroy(ctx context.Context) error {
 	if c.ID == "" {
 		return nil
@@ -281,7 +417,7 @@ func (c *Container) Wait(ctx context.Context) error {
 	if statusCode == 0 {
 		return nil
 	}
-	return fmt.Errorf("exit with `FAILURE`: %v", statusCode)
+	return &ExitError{Code: int(statusCode)}
 }
 
 func (c *Container) getAuthString() string {
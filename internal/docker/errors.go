@@ -0,0 +1,12 @@
+package docker
+
+import "fmt"
+
+// ExitError is returned when a command or a container exits with a non-zero code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exitcode '%d': failure", e.Code)
+}
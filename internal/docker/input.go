@@ -10,4 +10,7 @@ type Input struct {
 	HostDir      string
 	Envs         map[string]string
 	Entrypoint   []string
+	Labels       map[string]string
+	// memory limit in MB, no limit if 0
+	Memory int
 }
@@ -0,0 +1,19 @@
+package docker
+
+const (
+	// LabelManaged marks the docker resources created by bbp
+	LabelManaged = "com.github.zhex.local-bbp"
+	// LabelProject holds the host path of the project
+	LabelProject = "com.github.zhex.local-bbp.project"
+	// LabelRun holds the id of the run
+	LabelRun = "com.github.zhex.local-bbp.run"
+)
+
+// NewLabels returns the labels of the resources created for the run of the project
+func NewLabels(project, run string) map[string]string {
+	return map[string]string{
+		LabelManaged: "true",
+		LabelProject: project,
+		LabelRun:     run,
+	}
+}
@@ -9,17 +9,19 @@ import (
 type Network struct {
 	ID         string
 	Name       string
+	Labels     map[string]string
 	client     *client.Client
 	Containers []*Container
 }
 
-func NewNetwork(name string) *Network {
-	return &Network{Name: name, client: dockerClient}
+func NewNetwork(name string, labels map[string]string) *Network {
+	return &Network{Name: name, Labels: labels, client: dockerClient}
 }
 
 func (n *Network) Create(ctx context.Context) error {
 	resp, err := n.client.NetworkCreate(ctx, n.Name, network.CreateOptions{
 		Driver: "bridge",
+		Labels: n.Labels,
 	})
 	if err != nil {
 		return err
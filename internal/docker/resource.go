@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"time"
+)
+
+const (
+	ResourceContainer = "container"
+	ResourceNetwork   = "network"
+	ResourceVolume    = "volume"
+)
+
+// Resource is a docker container, network or volume created by bbp
+type Resource struct {
+	Type    string
+	ID      string
+	Name    string
+	Project string
+	Run     string
+	Created time.Time
+}
+
+// ListResources returns the resources created by bbp for the project, or for
+// all the projects if project is empty. Containers are listed first, then the
+// networks and volumes, which is the order they can be removed in.
+func ListResources(ctx context.Context, project string) ([]*Resource, error) {
+	args := filters.NewArgs(filters.Arg("label", LabelManaged))
+	if project != "" {
+		args.Add("label", LabelProject+"="+project)
+	}
+
+	var resources []*Resource
+
+	containers, err := dockerClient.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = c.Names[0][1:]
+		}
+		resources = append(resources, &Resource{
+			Type:    ResourceContainer,
+			ID:      c.ID,
+			Name:    name,
+			Project: c.Labels[LabelProject],
+			Run:     c.Labels[LabelRun],
+			Created: time.Unix(c.Created, 0),
+		})
+	}
+
+	networks, err := dockerClient.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range networks {
+		resources = append(resources, &Resource{
+			Type:    ResourceNetwork,
+			ID:      n.ID,
+			Name:    n.Name,
+			Project: n.Labels[LabelProject],
+			Run:     n.Labels[LabelRun],
+			Created: n.Created,
+		})
+	}
+
+	volumes, err := dockerClient.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes.Volumes {
+		created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		resources = append(resources, &Resource{
+			Type:    ResourceVolume,
+			ID:      v.Name,
+			Name:    v.Name,
+			Project: v.Labels[LabelProject],
+			Run:     v.Labels[LabelRun],
+			Created: created,
+		})
+	}
+
+	return resources, nil
+}
+
+// RemoveResource force removes the resource
+func RemoveResource(ctx context.Context, res *Resource) error {
+	switch res.Type {
+	case ResourceContainer:
+		return dockerClient.ContainerRemove(ctx, res.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	case ResourceNetwork:
+		return dockerClient.NetworkRemove(ctx, res.ID)
+	case ResourceVolume:
+		return dockerClient.VolumeRemove(ctx, res.ID, true)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultCloneDepth = 50
+
+// CloneDepthFull is the depth value for cloning the full history
+const CloneDepthFull = 0
+
+type Clone struct {
+	Enabled       *bool `yaml:"enabled"`
+	Depth         *int  `yaml:"depth"`
+	LFS           *bool `yaml:"lfs"`
+	SkipSSLVerify *bool `yaml:"skip-ssl-verify"`
+}
+
+func (c *Clone) UnmarshalYAML(value *yaml.Node) error {
+	var tmp struct {
+		Enabled       *bool     `yaml:"enabled"`
+		Depth         yaml.Node `yaml:"depth"`
+		LFS           *bool     `yaml:"lfs"`
+		SkipSSLVerify *bool     `yaml:"skip-ssl-verify"`
+	}
+	if err := value.Decode(&tmp); err != nil {
+		return err
+	}
+
+	c.Enabled = tmp.Enabled
+	c.LFS = tmp.LFS
+	c.SkipSSLVerify = tmp.SkipSSLVerify
+
+	if !tmp.Depth.IsZero() {
+		depth := CloneDepthFull
+		if tmp.Depth.Value != "full" {
+			if err := tmp.Depth.Decode(&depth); err != nil || depth < 1 {
+				return fmt.Errorf("invalid clone depth: %s", tmp.Depth.Value)
+			}
+		}
+		c.Depth = &depth
+	}
+	return nil
+}
+
+func (c *Clone) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// GetDepth returns the number of commits to clone, CloneDepthFull for the full history
+func (c *Clone) GetDepth() int {
+	if c.Depth == nil {
+		return DefaultCloneDepth
+	}
+	return *c.Depth
+}
+
+func (c *Clone) IsLFS() bool {
+	return c.LFS != nil && *c.LFS
+}
+
+func (c *Clone) IsSkipSSLVerify() bool {
+	return c.SkipSSLVerify != nil && *c.SkipSSLVerify
+}
+
+// Merge returns a copy of the clone settings overridden by the non-empty fields of other
+func (c *Clone) Merge(other *Clone) *Clone {
+	merged := *c
+	if other == nil {
+		return &merged
+	}
+	if other.Enabled != nil {
+		merged.Enabled = other.Enabled
+	}
+	if other.Depth != nil {
+		merged.Depth = other.Depth
+	}
+	if other.LFS != nil {
+		merged.LFS = other.LFS
+	}
+	if other.SkipSSLVerify != nil {
+		merged.SkipSSLVerify = other.SkipSSLVerify
+	}
+	return &merged
+}
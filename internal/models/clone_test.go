@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestClone_UnmarshalYAML(t *testing.T) {
+	data := `
+clone:
+  depth: full
+  lfs: true
+pipelines:
+  default:
+    - step:
+        clone:
+          depth: 5
+    - step:
+        clone:
+          enabled: false
+`
+	var plan Plan
+	err := yaml.Unmarshal([]byte(data), &plan)
+	assert.NoError(t, err)
+	assert.Equal(t, CloneDepthFull, plan.Clone.GetDepth())
+	assert.True(t, plan.Clone.IsLFS())
+
+	step1 := plan.GetStepClone(plan.Pipelines.Default[0].Step)
+	assert.True(t, step1.IsEnabled())
+	assert.Equal(t, 5, step1.GetDepth())
+	assert.True(t, step1.IsLFS())
+	assert.False(t, step1.IsSkipSSLVerify())
+
+	step2 := plan.GetStepClone(plan.Pipelines.Default[1].Step)
+	assert.False(t, step2.IsEnabled())
+	assert.Equal(t, CloneDepthFull, step2.GetDepth())
+
+	var c Clone
+	err = yaml.Unmarshal([]byte("depth: none"), &c)
+	assert.Error(t, err)
+
+	c = Clone{}
+	assert.True(t, c.IsEnabled())
+	assert.Equal(t, DefaultCloneDepth, c.GetDepth())
+}
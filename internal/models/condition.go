@@ -1,9 +1,43 @@
 package models
 
+import "github.com/bmatcuk/doublestar/v4"
+
 type ChangeSet struct {
 	IncludePaths []string `yaml:"includePaths"`
+	ExcludePaths []string `yaml:"excludePaths"`
+}
+
+// Match reports whether any of the changed files is included and not excluded
+func (c *ChangeSet) Match(changedFiles []string) bool {
+	if len(c.IncludePaths) == 0 && len(c.ExcludePaths) == 0 {
+		return true
+	}
+	for _, changedFile := range changedFiles {
+		included := len(c.IncludePaths) == 0 || matchAnyPath(c.IncludePaths, changedFile)
+		if included && !matchAnyPath(c.ExcludePaths, changedFile) {
+			return true
+		}
+	}
+	return false
 }
 
 type Condition struct {
 	ChangeSets ChangeSet `yaml:"changesets"`
 }
+
+func (c *Condition) Match(changedFiles []string) bool {
+	if c == nil {
+		return true
+	}
+	return c.ChangeSets.Match(changedFiles)
+}
+
+func matchAnyPath(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		matched, err := doublestar.PathMatch(pattern, file)
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
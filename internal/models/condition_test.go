@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestCondition_Match(t *testing.T) {
+	data := `
+changesets:
+  includePaths:
+    - "src/**"
+  excludePaths:
+    - "**/*.md"
+`
+	var c *Condition
+	_ = yaml.Unmarshal([]byte(data), &c)
+	assert.True(t, c.Match([]string{"src/main.go"}))
+	assert.True(t, c.Match([]string{"src/README.md", "src/main.go"}))
+	assert.False(t, c.Match([]string{"src/README.md"}))
+	assert.False(t, c.Match([]string{"docs/index.html"}))
+
+	data = `
+changesets:
+  excludePaths:
+    - "docs/**"
+`
+	var c2 *Condition
+	_ = yaml.Unmarshal([]byte(data), &c2)
+	assert.True(t, c2.Match([]string{"docs/index.html", "main.go"}))
+	assert.False(t, c2.Match([]string{"docs/index.html"}))
+	assert.False(t, c2.Match([]string{}))
+
+	var c3 *Condition
+	assert.True(t, c3.Match([]string{}))
+}
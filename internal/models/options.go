@@ -0,0 +1,7 @@
+package models
+
+type Options struct {
+	Docker  bool   `yaml:"docker"`
+	MaxTime int    `yaml:"max-time"`
+	Size    string `yaml:"size"`
+}
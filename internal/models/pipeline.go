@@ -1,9 +1,61 @@
 package models
 
+import (
+	"fmt"
+	"github.com/zhex/local-bbp/internal/common"
+	"gopkg.in/yaml.v3"
+)
+
 type Pipeline struct {
-	Default      []*Action            `yaml:"default"`
-	Branches     map[string][]*Action `yaml:"branches"`
-	PullRequests map[string][]*Action `yaml:"pull-requests"`
-	Tags         map[string][]*Action `yaml:"tags"`
-	Custom       map[string][]*Action `yaml:"custom"`
+	Default      []*Action                  `yaml:"default"`
+	Branches     map[string][]*Action       `yaml:"branches"`
+	PullRequests map[string][]*Action       `yaml:"pull-requests"`
+	Tags         map[string][]*Action       `yaml:"tags"`
+	Custom       map[string]*CustomPipeline `yaml:"custom"`
+}
+
+type Variable struct {
+	Name          string   `yaml:"name"`
+	Default       string   `yaml:"default"`
+	AllowedValues []string `yaml:"allowed-values"`
+	Description   string   `yaml:"description"`
+}
+
+func (v *Variable) Validate(value string) error {
+	if len(v.AllowedValues) > 0 && !common.Contains(v.AllowedValues, value) {
+		return fmt.Errorf("invalid value '%s' for variable %s, allowed values: %v", value, v.Name, v.AllowedValues)
+	}
+	return nil
+}
+
+type CustomPipeline struct {
+	Variables []*Variable
+	Actions   []*Action
+}
+
+func (c *CustomPipeline) UnmarshalYAML(value *yaml.Node) error {
+	var items []yaml.Node
+	if err := value.Decode(&items); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var vars struct {
+			Variables []*Variable `yaml:"variables"`
+		}
+		if err := item.Decode(&vars); err != nil {
+			return err
+		}
+		if vars.Variables != nil {
+			c.Variables = append(c.Variables, vars.Variables...)
+			continue
+		}
+
+		var action Action
+		if err := item.Decode(&action); err != nil {
+			return err
+		}
+		c.Actions = append(c.Actions, &action)
+	}
+	return nil
 }
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestCustomPipeline_UnmarshalYAML(t *testing.T) {
+	data := `
+- variables:
+    - name: VERSION
+      default: "1.0"
+      allowed-values:
+        - "1.0"
+        - "2.0"
+      description: The version to release
+    - name: ENV
+- step:
+    name: release
+    script:
+      - echo "$VERSION"
+`
+	var c CustomPipeline
+	err := yaml.Unmarshal([]byte(data), &c)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(c.Variables))
+	assert.Equal(t, "VERSION", c.Variables[0].Name)
+	assert.Equal(t, "1.0", c.Variables[0].Default)
+	assert.Equal(t, []string{"1.0", "2.0"}, c.Variables[0].AllowedValues)
+	assert.Equal(t, "The version to release", c.Variables[0].Description)
+	assert.Equal(t, 1, len(c.Actions))
+	assert.Equal(t, "release", c.Actions[0].Step.Name)
+
+	assert.NoError(t, c.Variables[0].Validate("2.0"))
+	assert.Error(t, c.Variables[0].Validate("3.0"))
+	assert.NoError(t, c.Variables[1].Validate("anything"))
+}
@@ -1,11 +1,18 @@
 package models
 
-import "strings"
+import (
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/zhex/local-bbp/internal/common"
+	"sort"
+	"strings"
+)
 
 type Plan struct {
 	DefaultImage *Image      `yaml:"image"`
 	Pipelines    *Pipeline   `yaml:"pipelines"`
 	Definitions  *Definition `yaml:"definitions"`
+	Options      *Options    `yaml:"options"`
+	Clone        *Clone      `yaml:"clone"`
 }
 
 func (p *Plan) GetPipeline(name string) []*Action {
@@ -26,11 +33,110 @@ func (p *Plan) GetPipeline(name string) []*Action {
 	}
 	if strings.HasPrefix(name, "custom/") {
 		name = strings.TrimPrefix(name, "custom/")
-		return p.Pipelines.Custom[name]
+		if custom := p.Pipelines.Custom[name]; custom != nil {
+			return custom.Actions
+		}
 	}
 	return nil
 }
 
+// HasParallelSteps reports whether the pipeline runs steps in parallel
+func (p *Plan) HasParallelSteps(name string) bool {
+	for _, action := range p.GetPipeline(name) {
+		if action.IsParallel() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPipelineVariables returns the variables declared by a custom pipeline
+func (p *Plan) GetPipelineVariables(name string) []*Variable {
+	if !strings.HasPrefix(name, "custom/") {
+		return nil
+	}
+	if custom := p.Pipelines.Custom[strings.TrimPrefix(name, "custom/")]; custom != nil {
+		return custom.Variables
+	}
+	return nil
+}
+
+// FindBranchPipeline returns the name of the pipeline that runs on a push
+// to the branch, falling back to the default pipeline
+func (p *Plan) FindBranchPipeline(branch string) string {
+	if key, ok := findPipelineKey(p.Pipelines.Branches, branch); ok {
+		return "branch/" + key
+	}
+	if p.Pipelines.Default != nil {
+		return "default"
+	}
+	return ""
+}
+
+// FindTagPipeline returns the name of the pipeline that runs on a push of the tag
+func (p *Plan) FindTagPipeline(tag string) string {
+	if key, ok := findPipelineKey(p.Pipelines.Tags, tag); ok {
+		return "tag/" + key
+	}
+	return ""
+}
+
+// FindPullRequestPipeline returns the name of the pipeline that runs on a pull
+// request from the source branch
+func (p *Plan) FindPullRequestPipeline(source string) string {
+	if key, ok := findPipelineKey(p.Pipelines.PullRequests, source); ok {
+		return "pr/" + key
+	}
+	return ""
+}
+
+// findPipelineKey finds the key of the pipelines matching the name with the
+// Bitbucket glob rules. An exact key wins over the patterns, and the most
+// specific pattern wins over the others.
+func findPipelineKey(pipelines map[string][]*Action, name string) (string, bool) {
+	if _, ok := pipelines[name]; ok {
+		return name, true
+	}
+	var matches []string
+	for key := range pipelines {
+		if matched, err := doublestar.Match(key, name); err == nil && matched {
+			matches = append(matches, key)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		si, sj := getPatternSpecificity(matches[i]), getPatternSpecificity(matches[j])
+		if si != sj {
+			return si > sj
+		}
+		return matches[i] < matches[j]
+	})
+	return matches[0], true
+}
+
+// getPatternSpecificity scores a glob pattern by its literal characters,
+// a double star matches more names than a single star
+func getPatternSpecificity(pattern string) int {
+	score := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				score -= 2
+				i++
+			} else {
+				score--
+			}
+		case '?', '{', '}', ',', '[', ']':
+		default:
+			score += 2
+		}
+	}
+	return score
+}
+
 func (p *Plan) GetPipelineNames() []string {
 	names := make([]string, 0)
 	if p.Pipelines.Default != nil {
@@ -62,3 +168,42 @@ func (p *Plan) GetCaches() Caches {
 func (p *Plan) HasImage() bool {
 	return p.DefaultImage != nil && p.DefaultImage.Name != ""
 }
+
+// GetStepServices returns the services of the step, including the docker
+// service when it is enabled for all steps in the global options
+func (p *Plan) GetStepServices(step *Step) []string {
+	services := step.Services
+	if p.Options != nil && p.Options.Docker && !common.Contains(services, "docker") {
+		services = append(append([]string{}, services...), "docker")
+	}
+	return services
+}
+
+// GetStepSize returns the size of the step, falling back to the global size
+func (p *Plan) GetStepSize(step *Step) string {
+	if step.Size == "" && p.Options != nil {
+		return p.Options.Size
+	}
+	return step.Size
+}
+
+// GetStepMaxTime returns the max time of the step in minutes, falling back to
+// the global max time and then the given default value
+func (p *Plan) GetStepMaxTime(step *Step, defaultTime int) int {
+	if step.MaxTime > 0 {
+		return step.MaxTime
+	}
+	if p.Options != nil && p.Options.MaxTime > 0 {
+		return p.Options.MaxTime
+	}
+	return defaultTime
+}
+
+// GetStepClone returns the clone settings of the step merged with the global settings
+func (p *Plan) GetStepClone(step *Step) *Clone {
+	clone := &Clone{}
+	if p.Clone != nil {
+		clone = p.Clone
+	}
+	return clone.Merge(step.Clone)
+}
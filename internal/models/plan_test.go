@@ -0,0 +1,127 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestPlan_Options(t *testing.T) {
+	data := `
+options:
+  docker: true
+  max-time: 30
+  size: 2x
+pipelines:
+  default:
+    - step:
+        script:
+          - echo "build"
+    - step:
+        size: 4x
+        max-time: 10
+        services:
+          - docker
+        script:
+          - echo "deploy"
+`
+	var plan Plan
+	err := yaml.Unmarshal([]byte(data), &plan)
+	assert.NoError(t, err)
+
+	build := plan.Pipelines.Default[0].Step
+	assert.Equal(t, []string{"docker"}, plan.GetStepServices(build))
+	assert.Equal(t, "2x", plan.GetStepSize(build))
+	assert.Equal(t, 30, plan.GetStepMaxTime(build, 120))
+
+	deploy := plan.Pipelines.Default[1].Step
+	assert.Equal(t, []string{"docker"}, plan.GetStepServices(deploy))
+	assert.Equal(t, "4x", plan.GetStepSize(deploy))
+	assert.Equal(t, 10, plan.GetStepMaxTime(deploy, 120))
+
+	plan.Options = nil
+	assert.Empty(t, plan.GetStepServices(build))
+	assert.Equal(t, "", plan.GetStepSize(build))
+	assert.Equal(t, 120, plan.GetStepMaxTime(build, 120))
+}
+
+func TestPlan_HasParallelSteps(t *testing.T) {
+	data := `
+pipelines:
+  default:
+    - step:
+        script:
+          - echo "build"
+    - parallel:
+        - step:
+            script:
+              - echo "test"
+        - step:
+            script:
+              - echo "lint"
+  custom:
+    deploy:
+      - step:
+          script:
+            - echo "deploy"
+`
+	var plan Plan
+	err := yaml.Unmarshal([]byte(data), &plan)
+	assert.NoError(t, err)
+
+	assert.True(t, plan.HasParallelSteps("default"))
+	assert.False(t, plan.HasParallelSteps("custom/deploy"))
+}
+
+func TestPlan_FindPipeline(t *testing.T) {
+	data := `
+pipelines:
+  default:
+    - step:
+        script:
+          - echo "default"
+  branches:
+    main:
+      - step:
+          script:
+            - echo "main"
+    "feature/*":
+      - step:
+          script:
+            - echo "feature"
+    "{release,hotfix}/**":
+      - step:
+          script:
+            - echo "release"
+    "**":
+      - step:
+          script:
+            - echo "all"
+  tags:
+    "v*":
+      - step:
+          script:
+            - echo "tag"
+  pull-requests:
+    "feature/*":
+      - step:
+          script:
+            - echo "pr"
+`
+	var plan Plan
+	err := yaml.Unmarshal([]byte(data), &plan)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "branch/main", plan.FindBranchPipeline("main"))
+	assert.Equal(t, "branch/feature/*", plan.FindBranchPipeline("feature/login"))
+	assert.Equal(t, "branch/**", plan.FindBranchPipeline("feature/login/fix"))
+	assert.Equal(t, "branch/{release,hotfix}/**", plan.FindBranchPipeline("hotfix/1.0/fix"))
+	assert.Equal(t, "branch/**", plan.FindBranchPipeline("develop"))
+	assert.Equal(t, "tag/v*", plan.FindTagPipeline("v1.0.0"))
+	assert.Equal(t, "", plan.FindTagPipeline("release-1"))
+	assert.Equal(t, "pr/feature/*", plan.FindPullRequestPipeline("feature/login"))
+	assert.Equal(t, "", plan.FindPullRequestPipeline("main"))
+
+	delete(plan.Pipelines.Branches, "**")
+	assert.Equal(t, "default", plan.FindBranchPipeline("develop"))
+}
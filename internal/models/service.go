@@ -10,3 +10,10 @@ type Service struct {
 func (s *Service) IsDockerService() bool {
 	return s.Type == "docker"
 }
+
+func (s *Service) GetMemory() int {
+	if s.Memory == 0 {
+		return DefaultServiceMemory
+	}
+	return s.Memory
+}
@@ -0,0 +1,27 @@
+package models
+
+import "fmt"
+
+// memory limits in MB, see https://support.atlassian.com/bitbucket-cloud/docs/databases-and-service-containers/
+const DefaultServiceMemory = 1024
+const MinServiceMemory = 128
+const MinBuildMemory = 1024
+
+var sizeMemory = map[string]int{
+	"1x": 4096,
+	"2x": 8192,
+	"4x": 16384,
+	"8x": 32768,
+}
+
+// GetSizeMemory returns the total memory of a step with the given size,
+// which is shared between the build container and its services
+func GetSizeMemory(size string) (int, error) {
+	if size == "" {
+		size = "1x"
+	}
+	if memory, ok := sizeMemory[size]; ok {
+		return memory, nil
+	}
+	return 0, fmt.Errorf("unsupported step size: %s", size)
+}
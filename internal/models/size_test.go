@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetSizeMemory(t *testing.T) {
+	memory, err := GetSizeMemory("")
+	assert.NoError(t, err)
+	assert.Equal(t, 4096, memory)
+
+	memory, err = GetSizeMemory("2x")
+	assert.NoError(t, err)
+	assert.Equal(t, 8192, memory)
+
+	_, err = GetSizeMemory("3x")
+	assert.Error(t, err)
+}
+
+func TestService_GetMemory(t *testing.T) {
+	svc := &Service{}
+	assert.Equal(t, DefaultServiceMemory, svc.GetMemory())
+
+	svc.Memory = 2048
+	assert.Equal(t, 2048, svc.GetMemory())
+}
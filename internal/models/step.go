@@ -1,7 +1,5 @@
 package models
 
-import "github.com/bmatcuk/doublestar/v4"
-
 type StepTrigger string
 
 const StepTriggerAutomatic StepTrigger = "automatic"
@@ -22,6 +20,7 @@ type Step struct {
 	Services    []string          `yaml:"services"`
 	RunsOn      []string          `yaml:"runs-on"`
 	Condition   *Condition        `yaml:"condition"`
+	Clone       *Clone            `yaml:"clone"`
 }
 
 func (s *Step) IsManual() bool {
@@ -40,16 +39,5 @@ func (s *Step) HasImage() bool {
 }
 
 func (s *Step) MatchCondition(changedFiles []string) bool {
-	if s.Condition == nil {
-		return true
-	}
-	for _, pattern := range s.Condition.ChangeSets.IncludePaths {
-		for _, changedFile := range changedFiles {
-			matched, err := doublestar.PathMatch(pattern, changedFile)
-			if err == nil && matched {
-				return true
-			}
-		}
-	}
-	return false
+	return s.Condition.Match(changedFiles)
 }
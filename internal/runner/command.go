@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/zhex/local-bbp/internal/common"
+	"github.com/zhex/local-bbp/internal/docker"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandMarker starts the lines that the script writes around its commands,
+// the lines are taken out of the output
+const commandMarker = "##bbp[command]"
+
+// CommandResult is the timing and the exit status of a command of the step
+type CommandResult struct {
+	Command string `json:"command"`
+	// phase of the step that the command runs in, the script or the after-script
+	Phase     string    `json:"phase"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	ExitCode  int       `json:"exitCode"`
+}
+
+func (c *CommandResult) GetDuration() time.Duration {
+	return c.EndTime.Sub(c.StartTime)
+}
+
+// GetTitle returns the first line of the command
+func (c *CommandResult) GetTitle() string {
+	title, _, _ := strings.Cut(strings.TrimSpace(c.Command), "\n")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(title), "\\"))
+}
+
+// newCommandScript returns the shell script that runs the commands. It marks
+// the start and the end of each command, and the exit status of the script
+// as the failed command ends it.
+func newCommandScript(cmds []string) string {
+	lines := []string{fmt.Sprintf(`trap 'echo "%s exit $?"' EXIT`, commandMarker)}
+	for i, c := range cmds {
+		lines = append(lines,
+			fmt.Sprintf("echo '%s start %d'", commandMarker, i),
+			fmt.Sprintf("echo '+ %s'", c),
+			c,
+			fmt.Sprintf("echo '%s end'", commandMarker),
+			"echo '\n'",
+		)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// commandWriter records the commands of the step from the markers in the
+// output, and writes the other lines to the output with the end of each command
+type commandWriter struct {
+	out     io.Writer
+	sr      *StepResult
+	cmds    []string
+	phase   string
+	current *CommandResult
+	buf     []byte
+}
+
+func newCommandWriter(out io.Writer, sr *StepResult, cmds []string) *commandWriter {
+	return &commandWriter{
+		out:   out,
+		sr:    sr,
+		cmds:  cmds,
+		phase: sr.GetPhase(),
+	}
+}
+
+func (w *commandWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *commandWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLine(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *commandWriter) writeLine(line []byte) error {
+	i := bytes.Index(line, []byte(commandMarker))
+	if i < 0 {
+		_, err := w.out.Write(line)
+		return err
+	}
+	// the output of the command does not end with a newline
+	if i > 0 {
+		if _, err := w.out.Write(append(line[:i:i], '\n')); err != nil {
+			return err
+		}
+	}
+
+	fields := strings.Fields(string(line[i+len(commandMarker):]))
+	if len(fields) == 0 {
+		return nil
+	}
+	switch fields[0] {
+	case "start":
+		if len(fields) < 2 {
+			return nil
+		}
+		idx, err := strconv.Atoi(fields[1])
+		if err != nil || idx < 0 || idx >= len(w.cmds) {
+			return nil
+		}
+		w.current = &CommandResult{Command: w.cmds[idx], Phase: w.phase, StartTime: time.Now()}
+		w.sr.AddCommand(w.current)
+	case "end":
+		return w.endCommand(0)
+	case "exit":
+		if len(fields) < 2 {
+			return nil
+		}
+		if code, err := strconv.Atoi(fields[1]); err == nil {
+			return w.endCommand(code)
+		}
+	}
+	return nil
+}
+
+// endCommand records the end of the running command and writes it to the output
+func (w *commandWriter) endCommand(exitCode int) error {
+	c := w.current
+	if c == nil {
+		return nil
+	}
+	w.current = nil
+	c.EndTime = time.Now()
+	c.ExitCode = exitCode
+
+	d := c.GetDuration().Round(time.Millisecond)
+	var line string
+	if exitCode == 0 {
+		line = common.ColorGrey(fmt.Sprintf("Command finished in %s", d))
+	} else {
+		line = common.ColorRed(fmt.Sprintf("Command failed with exit code %d after %s", exitCode, d))
+	}
+	_, err := w.out.Write([]byte(line + "\n"))
+	return err
+}
+
+// Close ends the command that is still running when the script is killed,
+// e.g. on timeout, with the error of the script
+func (w *commandWriter) Close(err error) {
+	c := w.current
+	if c == nil {
+		return
+	}
+	w.current = nil
+	c.EndTime = time.Now()
+	var exitErr *docker.ExitError
+	if errors.As(err, &exitErr) {
+		c.ExitCode = exitErr.Code
+	} else if err != nil {
+		c.ExitCode = 1
+	}
+}
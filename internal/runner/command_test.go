@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhex/local-bbp/internal/common"
+	"os/exec"
+	"testing"
+)
+
+func TestCommandWriter(t *testing.T) {
+	sr := NewResult("default", nil).AddStep(1, "build", nil)
+	sr.SetPhase(PhaseScript)
+
+	cmds := []string{"echo hello", "printf partial; exit 3", "echo never"}
+	out := &bytes.Buffer{}
+	cw := newCommandWriter(out, sr, cmds)
+
+	cmd := exec.Command("sh", "-ce", newCommandScript(cmds))
+	cmd.Stdout = cw
+	err := cmd.Run()
+	assert.Error(t, err)
+	assert.NoError(t, cw.Flush())
+	cw.Close(err)
+
+	assert.Len(t, sr.Commands, 2)
+	assert.Equal(t, "echo hello", sr.Commands[0].Command)
+	assert.Equal(t, 0, sr.Commands[0].ExitCode)
+	assert.False(t, sr.Commands[0].EndTime.IsZero())
+	assert.Equal(t, 3, sr.Commands[1].ExitCode)
+	assert.Equal(t, PhaseScript, sr.Commands[1].Phase)
+	assert.Equal(t, sr.Commands[1], sr.GetFailedCommand())
+	assert.Regexp(t, `^Step \[1\] build failed at command: printf partial; exit 3 \(exit code 3 after \S+\)$`, sr.GetFailedCommandMessage())
+
+	log := common.StripANSI(out.String())
+	assert.NotContains(t, log, commandMarker)
+	assert.Regexp(t, `^\+ echo hello\nhello\nCommand finished in \S+\n`, log)
+	assert.Regexp(t, `\+ printf partial; exit 3\npartial\nCommand failed with exit code 3 after \S+\n$`, log)
+}
+
+func TestCommandWriter_Close(t *testing.T) {
+	sr := NewResult("default", nil).AddStep(1, "build", nil)
+	cw := newCommandWriter(&bytes.Buffer{}, sr, []string{"sleep 600"})
+	_, _ = cw.Write([]byte(commandMarker + " start 0\r\n"))
+
+	// the script is killed before the command ends
+	cw.Close(context.DeadlineExceeded)
+	assert.Len(t, sr.Commands, 1)
+	assert.Equal(t, 1, sr.Commands[0].ExitCode)
+	assert.False(t, sr.Commands[0].EndTime.IsZero())
+}
+
+func TestCommandResult_GetTitle(t *testing.T) {
+	c := &CommandResult{Command: "docker run --rm \\\n  -v /src:/src \\\n  alpine"}
+	assert.Equal(t, "docker run --rm", c.GetTitle())
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/moby/term"
+	"github.com/zhex/local-bbp/internal/common"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dashboard renders the step results of a running pipeline in the terminal.
+// Use the up and down keys to select a step, and enter to follow its log.
+type Dashboard struct {
+	result   *Result
+	out      *os.File
+	in       *os.File
+	keys     *common.CancelableReader
+	state    *term.State
+	selected int
+	expanded bool
+	lock     sync.Mutex
+	done     chan struct{}
+	wg       sync.WaitGroup
+	keysDone chan struct{}
+}
+
+func NewDashboard(result *Result) *Dashboard {
+	return &Dashboard{
+		result:   result,
+		out:      os.Stdout,
+		in:       os.Stdin,
+		done:     make(chan struct{}),
+		keysDone: make(chan struct{}),
+	}
+}
+
+// IsDashboardSupported reports whether stdout is a terminal to render the dashboard
+func IsDashboardSupported() bool {
+	return term.IsTerminal(os.Stdout.Fd())
+}
+
+func (d *Dashboard) Start() error {
+	if term.IsTerminal(d.in.Fd()) {
+		state, err := term.SetRawTerminal(d.in.Fd())
+		if err != nil {
+			return err
+		}
+		d.state = state
+		d.keys = common.NewCancelableReader(d.in)
+		go d.readKeys()
+	} else {
+		close(d.keysDone)
+	}
+	// hide the cursor
+	_, _ = fmt.Fprint(d.out, "\x1b[?25l")
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			d.render()
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *Dashboard) Stop() {
+	d.stop(true)
+}
+
+// stop stops the rendering and the key reading, waiting for the key reader
+// to return so it does not take the input of the next reader of stdin
+func (d *Dashboard) stop(waitKeys bool) {
+	select {
+	case <-d.done:
+		return
+	default:
+		close(d.done)
+	}
+	if d.keys != nil {
+		d.keys.Cancel()
+		if waitKeys {
+			<-d.keysDone
+		}
+	}
+	d.wg.Wait()
+	d.render()
+	if d.state != nil {
+		_ = term.RestoreTerminal(d.in.Fd(), d.state)
+	}
+	// show the cursor
+	_, _ = fmt.Fprint(d.out, "\x1b[?25h\n")
+}
+
+func (d *Dashboard) readKeys() {
+	defer close(d.keysDone)
+	buf := make([]byte, 8)
+	for {
+		n, err := d.keys.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+		key := string(buf[:n])
+		d.lock.Lock()
+		switch key {
+		case "\x1b[A", "k":
+			if d.selected > 0 {
+				d.selected--
+			}
+		case "\x1b[B", "j":
+			if d.selected < len(d.result.StepResults)-1 {
+				d.selected++
+			}
+		case "\r", "\n", " ":
+			d.expanded = !d.expanded
+		case "\x03":
+			d.lock.Unlock()
+			d.interrupt()
+			return
+		}
+		d.lock.Unlock()
+		d.render()
+	}
+}
+
+// interrupt restores the terminal and sends the interrupt signal that the raw
+// mode swallows, it runs in the key reader so it does not wait for it
+func (d *Dashboard) interrupt() {
+	d.stop(false)
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		_ = p.Signal(os.Interrupt)
+	}
+}
+
+func (d *Dashboard) getSteps() []*StepResult {
+	steps := make([]*StepResult, 0, len(d.result.StepResults))
+	for _, sr := range d.result.StepResults {
+		steps = append(steps, sr)
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].Index < steps[j].Index
+	})
+	return steps
+}
+
+func (d *Dashboard) render() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	width, height := 80, 24
+	if ws, err := term.GetWinsize(d.out.Fd()); err == nil && ws.Width > 0 {
+		width, height = int(ws.Width), int(ws.Height)
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf(
+		"%s Pipeline: %s  Status: %s  Elapsed: %s",
+		common.ColorGrey(fmt.Sprintf("[%s]", d.result.ID)),
+		d.result.EventName,
+		GetColoredStatus(d.result.GetStatus()),
+		d.result.GetDuration().Round(time.Second),
+	))
+	lines = append(lines, "")
+
+	steps := d.getSteps()
+	for i, sr := range steps {
+		cursor := "  "
+		if i == d.selected {
+			cursor = "> "
+		}
+		status := sr.GetStatus()
+		if sr.IsRunning() && status == StatusPending {
+			status = "running"
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%s%-6s %-30s %s %-20s %s",
+			cursor,
+			sr.GetIdxString(),
+			truncate(sr.Name, 30),
+			getPaddedStatus(status, 9),
+			sr.GetPhase(),
+			sr.GetDuration().Round(time.Second),
+		))
+	}
+
+	lines = append(lines, "")
+	if d.expanded && d.selected < len(steps) {
+		sr := steps[d.selected]
+		lines = append(lines, common.ColorCyan(fmt.Sprintf("Log of step [%s] %s:", sr.GetIdxString(), sr.Name)))
+		size := height - len(lines) - 2
+		if size > 0 {
+			logLines, _ := common.ReadLastLines(sr.GetLogPath(), size)
+			for _, line := range logLines {
+				_, text := ParseLogLine(line)
+				lines = append(lines, truncate(common.StripANSI(text), width))
+			}
+		}
+	} else {
+		lines = append(lines, common.ColorGrey("up/down: select step, enter: follow log, ctrl-c: stop"))
+	}
+
+	// lines end with \r\n as the raw terminal mode does not return the carriage
+	b := &bytes.Buffer{}
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(strings.Join(lines, "\r\n"))
+	_, _ = d.out.Write(b.Bytes())
+}
+
+func truncate(text string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+	return string(runes[:size])
+}
@@ -14,6 +14,9 @@ type ProjectInfo struct {
 	RepoID     string
 	BranchName string
 	CommitID   string
+	Tag        string
+	// destination branch of the pull request
+	DestinationBranch string
 }
 
 func NewProjInfo(hostPath string) *ProjectInfo {
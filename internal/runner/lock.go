@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"os"
+	"path"
+)
+
+// RunLockFile is locked by the process of a run while the run is in progress.
+// The system releases the lock when the process ends, even when it crashes or
+// is killed, which tells a run in progress from a run left pending.
+const RunLockFile = "run.lock"
+
+// LockRun locks the lock file in the folder of the run, the returned function
+// releases and removes it
+func LockRun(outputDir, id string) (func(), error) {
+	p := path.Join(outputDir, id, RunLockFile)
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return func() {
+		_ = os.Remove(p)
+		_ = file.Close()
+	}, nil
+}
+
+// IsRunActive reports whether the process of the run still holds its lock
+func IsRunActive(outputDir, id string) bool {
+	file, err := os.Open(path.Join(outputDir, id, RunLockFile))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_SH|unix.LOCK_NB); err != nil {
+		return errors.Is(err, unix.EWOULDBLOCK)
+	}
+	_ = unix.Flock(int(file.Fd()), unix.LOCK_UN)
+	return false
+}
@@ -0,0 +1,22 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLockRun(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "r-1"), 0755))
+	assert.False(t, IsRunActive(dir, "r-1"))
+
+	unlock, err := LockRun(dir, "r-1")
+	assert.NoError(t, err)
+	assert.True(t, IsRunActive(dir, "r-1"))
+
+	unlock()
+	assert.False(t, IsRunActive(dir, "r-1"))
+	assert.NoFileExists(t, path.Join(dir, "r-1", RunLockFile))
+}
@@ -29,7 +29,10 @@ func WithLoggerComposeStepResult(ctx context.Context, sr *StepResult) context.Co
 }
 
 func GetLogger(ctx context.Context) logrus.FieldLogger {
-	return ctx.Value(loggerKey).(logrus.FieldLogger)
+	if logger, ok := ctx.Value(loggerKey).(logrus.FieldLogger); ok {
+		return logger
+	}
+	return logrus.StandardLogger()
 }
 
 type runnerLoggerFormatter struct {
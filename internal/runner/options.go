@@ -0,0 +1,30 @@
+package runner
+
+type Options struct {
+	// output mode of the step scripts, one of OutputModes
+	Output string
+	// show the terminal dashboard instead of the plain output
+	TUI bool
+	// run manual steps without asking for approval
+	AutoApprove bool
+	// pause the pipeline at the first manual step without asking
+	StopAtManual bool
+	// build the workdir from the committed files only
+	Clean bool
+	// include uncommitted changes when matching the changesets conditions
+	UncommittedChanges bool
+	// values of the custom pipeline variables
+	Variables map[string]string
+	// selects the steps to run, the other steps are skipped. All the steps run if nil
+	StepFilter func(sr *StepResult) bool
+	// run that the artifacts of the skipped steps are taken from
+	PreviousResult *Result
+	// directory downloaded into the steps as an artifact
+	ArtifactsDir string
+	// open a shell in the build container when the script of a step fails
+	DebugOnFailure bool
+	// open a shell in the build container instead of running the script of the steps
+	Interactive bool
+	// reports written at the end of the run
+	Reports []*ReportTarget
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const OutputStream = "stream"
+const OutputFile = "file"
+const OutputQuiet = "quiet"
+
+var OutputModes = []string{OutputStream, OutputFile, OutputQuiet}
+
+// logTimeFormat is the fixed width timestamp that the lines of the step logs start with
+const logTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+// outputLock is shared by all the prefix writers, so lines of parallel steps do not tear
+var outputLock sync.Mutex
+
+// prefixWriter writes complete lines to the output with a prefix, partial lines
+// are buffered until the next newline or a flush
+type prefixWriter struct {
+	out       io.Writer
+	prefix    string
+	timestamp bool
+	buf       []byte
+}
+
+func newPrefixWriter(out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{
+		out:    out,
+		prefix: prefix,
+	}
+}
+
+// newTimestampWriter returns a prefix writer that starts the lines with the current time
+func newTimestampWriter(out io.Writer) *prefixWriter {
+	return &prefixWriter{
+		out:       out,
+		timestamp: true,
+	}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLine(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	line = bytes.TrimRight(line, "\r")
+	data := make([]byte, 0, len(logTimeFormat)+len(w.prefix)+len(line)+2)
+	if w.timestamp {
+		data = time.Now().UTC().AppendFormat(data, logTimeFormat)
+		data = append(data, ' ')
+	}
+	data = append(data, w.prefix...)
+	data = append(data, line...)
+	data = append(data, '\n')
+
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	_, err := w.out.Write(data)
+	return err
+}
+
+// ParseLogLine splits a line of the step log into its timestamp and text,
+// the timestamp is zero for lines without one
+func ParseLogLine(line string) (time.Time, string) {
+	if len(line) <= len(logTimeFormat) || line[len(logTimeFormat)] != ' ' {
+		return time.Time{}, line
+	}
+	t, err := time.Parse(logTimeFormat, line[:len(logTimeFormat)])
+	if err != nil {
+		return time.Time{}, line
+	}
+	return t, strings.TrimPrefix(line[len(logTimeFormat):], " ")
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newPrefixWriter(&buf, "[1] ")
+
+	_, _ = w.Write([]byte("hello\r\nwor"))
+	assert.Equal(t, "[1] hello\n", buf.String())
+
+	_, _ = w.Write([]byte("ld\nfoo"))
+	assert.Equal(t, "[1] hello\n[1] world\n", buf.String())
+
+	_ = w.Flush()
+	assert.Equal(t, "[1] hello\n[1] world\n[1] foo\n", buf.String())
+}
+
+func TestTimestampWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newTimestampWriter(&buf)
+
+	start := time.Now().Truncate(time.Microsecond)
+	_, _ = w.Write([]byte("hello\nworld"))
+	_ = w.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	ts, text := ParseLogLine(lines[0])
+	assert.Equal(t, "hello", text)
+	assert.False(t, ts.Before(start))
+
+	_, text = ParseLogLine(lines[1])
+	assert.Equal(t, "world", text)
+}
+
+func TestParseLogLine(t *testing.T) {
+	ts, text := ParseLogLine("2024-05-01T10:20:30.123456Z + npm test")
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 20, 30, 123456000, time.UTC), ts)
+	assert.Equal(t, "+ npm test", text)
+
+	ts, text = ParseLogLine("2024-05-01T10:20:30.123456Z ")
+	assert.False(t, ts.IsZero())
+	assert.Equal(t, "", text)
+
+	ts, text = ParseLogLine("plain line")
+	assert.True(t, ts.IsZero())
+	assert.Equal(t, "plain line", text)
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"context"
+	"time"
+)
+
+const PhasePull = "pull"
+const PhaseSetup = "setup"
+const PhaseClone = "clone"
+const PhaseCacheRestore = "cache restore"
+const PhaseArtifactsDownload = "artifacts download"
+const PhaseScript = "script"
+const PhaseShell = "shell"
+const PhaseTestReports = "test reports"
+const PhaseArtifactsSave = "artifacts save"
+const PhaseCacheSave = "cache save"
+const PhaseDebug = "debug"
+const PhaseAfterScript = "after-script"
+const PhaseTeardown = "teardown"
+
+// phaseOrder is the order in which the phases run in a step
+var phaseOrder = []string{
+	PhasePull,
+	PhaseSetup,
+	PhaseClone,
+	PhaseCacheRestore,
+	PhaseArtifactsDownload,
+	PhaseScript,
+	PhaseShell,
+	PhaseTestReports,
+	PhaseArtifactsSave,
+	PhaseCacheSave,
+	PhaseDebug,
+	PhaseAfterScript,
+	PhaseTeardown,
+}
+
+// PhaseResult is the timing of a phase of the step
+type PhaseResult struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+func (p *PhaseResult) GetDuration() time.Duration {
+	return p.EndTime.Sub(p.StartTime)
+}
+
+// WithPhase marks the step running in the phase while the task runs and
+// records the timing of the phase
+func WithPhase(task Task, sr *StepResult, phase string) Task {
+	return func(ctx context.Context) error {
+		sr.SetPhase(phase)
+		start := time.Now()
+		err := task(ctx)
+		sr.AddPhase(&PhaseResult{Name: phase, StartTime: start, EndTime: time.Now()})
+		return err
+	}
+}
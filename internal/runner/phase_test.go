@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWithPhase(t *testing.T) {
+	sr := NewResult("default", nil).AddStep(1, "build", nil)
+
+	var phase string
+	task := WithPhase(func(ctx context.Context) error {
+		phase = sr.GetPhase()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, sr, PhaseScript)
+	assert.NoError(t, task(context.Background()))
+	assert.Equal(t, PhaseScript, phase)
+
+	// failed phases are timed too
+	task = WithPhase(func(ctx context.Context) error {
+		return errors.New("failed")
+	}, sr, PhaseCacheSave)
+	assert.EqualError(t, task(context.Background()), "failed")
+
+	assert.Len(t, sr.Phases, 2)
+	assert.Equal(t, PhaseScript, sr.Phases[0].Name)
+	assert.GreaterOrEqual(t, sr.Phases[0].GetDuration(), 10*time.Millisecond)
+	assert.Equal(t, PhaseCacheSave, sr.Phases[1].Name)
+
+	durations := sr.GetPhaseDurations()
+	assert.Len(t, durations, 2)
+	assert.Equal(t, sr.Phases[0].GetDuration(), durations[PhaseScript])
+}
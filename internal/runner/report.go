@@ -0,0 +1,337 @@
+package runner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/zhex/local-bbp/internal/common"
+	"github.com/zhex/local-bbp/internal/testreport"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const ReportJSON = "json"
+const ReportJUnit = "junit"
+const ReportMarkdown = "markdown"
+
+var ReportFormats = []string{ReportJSON, ReportJUnit, ReportMarkdown}
+
+// reportLogLines is the number of log lines in the excerpt of a failed step
+const reportLogLines = 30
+
+var reportFileNames = map[string]string{
+	ReportJSON:     "report.json",
+	ReportJUnit:    "report.xml",
+	ReportMarkdown: "report.md",
+}
+
+// ReportTarget is a report to write at the end of the run, into the run folder
+// if the path is empty
+type ReportTarget struct {
+	Format string
+	Path   string
+}
+
+// ParseReportTarget parses the report in the format <format>[=<path>]
+func ParseReportTarget(s string) (*ReportTarget, error) {
+	format, p, _ := strings.Cut(s, "=")
+	if !common.Contains(ReportFormats, format) {
+		return nil, fmt.Errorf("invalid report format: %s, expect one of %s", format, strings.Join(ReportFormats, ", "))
+	}
+	return &ReportTarget{Format: format, Path: p}, nil
+}
+
+type Report struct {
+	ID        string        `json:"id"`
+	Pipeline  string        `json:"pipeline"`
+	Status    Status        `json:"status"`
+	Branch    string        `json:"branch,omitempty"`
+	Commit    string        `json:"commit,omitempty"`
+	StartTime time.Time     `json:"startTime"`
+	EndTime   time.Time     `json:"endTime"`
+	Duration  float64       `json:"duration"`
+	Steps     []*StepReport `json:"steps"`
+}
+
+type StepReport struct {
+	Index    string  `json:"index"`
+	Name     string  `json:"name"`
+	Group    string  `json:"group,omitempty"`
+	Status   Status  `json:"status"`
+	Manual   bool    `json:"manual"`
+	Image    string  `json:"image,omitempty"`
+	Duration float64 `json:"duration"`
+	ExitCode int     `json:"exitCode"`
+	LogPath  string  `json:"logPath"`
+	// first line of the command that failed the step
+	FailedCommand string `json:"failedCommand,omitempty"`
+	// last lines of the log of a failed or timed out step
+	LogExcerpt []string            `json:"logExcerpt,omitempty"`
+	Tests      *testreport.Summary `json:"tests,omitempty"`
+	Phases     []*PhaseReport      `json:"phases,omitempty"`
+}
+
+type PhaseReport struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"duration"`
+}
+
+// NewReport builds the report of the result, durations are in seconds
+func NewReport(result *Result) *Report {
+	report := &Report{
+		ID:        result.ID,
+		Pipeline:  result.EventName,
+		Status:    result.Status,
+		Branch:    result.Branch,
+		Commit:    result.Commit,
+		StartTime: result.StartTime,
+		EndTime:   result.EndTime,
+		Duration:  result.GetDuration().Seconds(),
+	}
+	for _, sr := range result.GetSteps() {
+		step := &StepReport{
+			Index:    sr.GetIdxString(),
+			Name:     sr.Name,
+			Group:    sr.Group,
+			Status:   sr.Status,
+			Manual:   sr.Step != nil && sr.Step.IsManual(),
+			Image:    sr.Image,
+			Duration: sr.GetDuration().Seconds(),
+			LogPath:  sr.GetLogPath(),
+			Tests:    sr.Tests,
+		}
+		for _, p := range sr.Phases {
+			step.Phases = append(step.Phases, &PhaseReport{Name: p.Name, Duration: p.GetDuration().Seconds()})
+		}
+		if sr.Status == StatusFailed || sr.Status == StatusTimeout {
+			step.ExitCode = sr.ExitCode
+			if c := sr.GetFailedCommand(); c != nil {
+				step.FailedCommand = c.GetTitle()
+			}
+			step.LogExcerpt = readLogExcerpt(sr.GetLogPath(), reportLogLines)
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	return report
+}
+
+// readLogExcerpt returns the last n lines of the log without the timestamps and colors
+func readLogExcerpt(logPath string, n int) []string {
+	lines, err := common.ReadLastLines(logPath, n)
+	if err != nil {
+		return nil
+	}
+	for i, line := range lines {
+		_, text := ParseLogLine(line)
+		lines[i] = common.StripANSI(text)
+	}
+	return lines
+}
+
+// writeReports writes the reports of the options and returns their paths
+func (r *Runner) writeReports(result *Result) ([]string, error) {
+	if len(r.Options.Reports) == 0 {
+		return nil, nil
+	}
+	report := NewReport(result)
+
+	var paths []string
+	for _, target := range r.Options.Reports {
+		p := target.Path
+		if p == "" {
+			p = path.Join(result.GetResultPath(), reportFileNames[target.Format])
+		}
+
+		var data []byte
+		var err error
+		switch target.Format {
+		case ReportJSON:
+			data, err = json.MarshalIndent(report, "", "  ")
+		case ReportJUnit:
+			data, err = report.JUnit()
+		case ReportMarkdown:
+			data = report.Markdown()
+		}
+		if err != nil {
+			return paths, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return paths, err
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr"`
+	Cases     []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit returns the report as JUnit XML, with a test case for each step
+func (r *Report) JUnit() ([]byte, error) {
+	suite := &junitTestSuite{
+		Name:      r.Pipeline,
+		Time:      formatSeconds(r.Duration),
+		Timestamp: r.StartTime.Format(time.RFC3339),
+	}
+	for _, step := range r.Steps {
+		tc := &junitTestCase{
+			Name:      fmt.Sprintf("%s %s", step.Index, step.Name),
+			ClassName: r.Pipeline,
+			Time:      formatSeconds(step.Duration),
+		}
+		switch step.Status {
+		case StatusSuccess:
+		case StatusFailed:
+			message := fmt.Sprintf("step failed with exit code %d", step.ExitCode)
+			if step.FailedCommand != "" {
+				message += " at command: " + step.FailedCommand
+			}
+			tc.Failure = &junitMessage{
+				Message: message,
+				Text:    strings.Join(step.LogExcerpt, "\n"),
+			}
+			suite.Failures++
+		case StatusTimeout:
+			tc.Error = &junitMessage{
+				Message: "step timed out",
+				Text:    strings.Join(step.LogExcerpt, "\n"),
+			}
+			suite.Errors++
+		case StatusStopped:
+			tc.Error = &junitMessage{Message: "step stopped"}
+			suite.Errors++
+		default:
+			message := "step " + string(step.Status)
+			if step.Manual {
+				message = "manual " + message
+			}
+			tc.Skipped = &junitMessage{Message: message}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+
+	suites := &junitTestSuites{
+		Name:     r.ID,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []*junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Markdown returns the report as a Markdown summary
+func (r *Report) Markdown() []byte {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "## Pipeline `%s`: %s\n\n", r.Pipeline, r.Status)
+	fmt.Fprintf(b, "Run `%s`", r.ID)
+	if r.Branch != "" {
+		fmt.Fprintf(b, " on `%s`", r.Branch)
+	}
+	if r.Commit != "" {
+		fmt.Fprintf(b, " at `%.7s`", r.Commit)
+	}
+	fmt.Fprintf(b, ", took %s.\n\n", formatDuration(r.Duration))
+
+	b.WriteString("| Step | Status | Duration | Exit code |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, step := range r.Steps {
+		status := string(step.Status)
+		if step.Manual {
+			status += " (manual)"
+		}
+		exitCode := ""
+		if step.Status == StatusFailed {
+			exitCode = fmt.Sprintf("%d", step.ExitCode)
+		}
+		fmt.Fprintf(b, "| %s %s | %s | %s | %s |\n", step.Index, escapeMarkdownCell(step.Name), status, formatDuration(step.Duration), exitCode)
+	}
+
+	for _, step := range r.Steps {
+		switch step.Status {
+		case StatusFailed:
+			fmt.Fprintf(b, "\n### Step `%s %s` failed with exit code %d\n\n", step.Index, step.Name, step.ExitCode)
+		case StatusTimeout:
+			fmt.Fprintf(b, "\n### Step `%s %s` timed out\n\n", step.Index, step.Name)
+		default:
+			continue
+		}
+		if step.FailedCommand != "" {
+			fmt.Fprintf(b, "Failed command: `%s`\n\n", step.FailedCommand)
+		}
+		if step.Tests != nil && len(step.Tests.Failures) > 0 {
+			fmt.Fprintf(b, "%d of %d tests failed:\n\n", step.Tests.Failed, step.Tests.Total)
+			for _, failure := range step.Tests.Failures {
+				fmt.Fprintf(b, "- `%s`: %s\n", failure.FullName(), failure.Message)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("```\n")
+		for _, line := range step.LogExcerpt {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		b.WriteString("```\n")
+	}
+	return []byte(b.String())
+}
+
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+func formatDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
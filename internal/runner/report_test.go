@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zhex/local-bbp/internal/config"
+	"github.com/zhex/local-bbp/internal/models"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestReportResult(t *testing.T) *Result {
+	dir := t.TempDir()
+	r := New(dir, &config.Config{OutputDir: dir}, nil)
+	result := NewResult("default", r)
+	result.ID = "r-1"
+	result.Status = StatusFailed
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	build := result.AddStep(1, "build", &models.Step{})
+	build.Status = StatusSuccess
+	build.StartTime = start
+	build.EndTime = start.Add(90 * time.Second)
+
+	test := result.AddStep(2, "test", &models.Step{})
+	test.Status = StatusFailed
+	test.ExitCode = 2
+	test.StartTime = build.EndTime
+	test.EndTime = test.StartTime.Add(10 * time.Second)
+
+	deploy := result.AddStep(3, "deploy", &models.Step{Trigger: models.StepTriggerManual})
+	deploy.Status = StatusPaused
+
+	_ = os.MkdirAll(path.Join(result.GetResultPath(), "logs"), 0755)
+	_ = os.WriteFile(test.GetLogPath(), []byte("2024-05-01T10:01:30.000000Z + npm test\n2024-05-01T10:01:40.000000Z \x1b[31m1 test failed\x1b[0m\n"), 0644)
+	return result
+}
+
+func TestNewReport(t *testing.T) {
+	report := NewReport(newTestReportResult(t))
+	assert.Equal(t, 100.0, report.Duration)
+	assert.Len(t, report.Steps, 3)
+	assert.Equal(t, 90.0, report.Steps[0].Duration)
+	assert.Nil(t, report.Steps[0].LogExcerpt)
+	assert.Equal(t, 2, report.Steps[1].ExitCode)
+	assert.Equal(t, []string{"+ npm test", "1 test failed"}, report.Steps[1].LogExcerpt)
+}
+
+func TestReport_JUnit(t *testing.T) {
+	data, err := NewReport(newTestReportResult(t)).JUnit()
+	assert.NoError(t, err)
+	xml := string(data)
+	assert.Contains(t, xml, `<testsuites name="r-1" tests="3" failures="1" errors="0" skipped="1" time="100.000">`)
+	assert.Contains(t, xml, `<testcase name="1 build" classname="default" time="90.000"></testcase>`)
+	assert.Contains(t, xml, `<failure message="step failed with exit code 2">+ npm test&#xA;1 test failed</failure>`)
+	assert.Contains(t, xml, `<skipped message="manual step paused"></skipped>`)
+}
+
+func TestReport_JUnitTimeout(t *testing.T) {
+	result := newTestReportResult(t)
+	result.AddStep(2.1, "e2e", &models.Step{}).Status = StatusTimeout
+	result.AddStep(4, "release", &models.Step{}).Status = StatusNotRun
+
+	data, err := NewReport(result).JUnit()
+	assert.NoError(t, err)
+	xml := string(data)
+	assert.Contains(t, xml, `<testsuites name="r-1" tests="5" failures="1" errors="1" skipped="2" time="100.000">`)
+	assert.Contains(t, xml, `<error message="step timed out"></error>`)
+	assert.Contains(t, xml, `<skipped message="step not_run"></skipped>`)
+}
+
+func TestReport_Markdown(t *testing.T) {
+	md := string(NewReport(newTestReportResult(t)).Markdown())
+	assert.True(t, strings.HasPrefix(md, "## Pipeline `default`: failed\n"))
+	assert.Contains(t, md, "| 1 build | success | 1m30s |  |\n")
+	assert.Contains(t, md, "| 3 deploy | paused (manual) | 0s |  |\n")
+	assert.Contains(t, md, "### Step `2 test` failed with exit code 2\n\n```\n+ npm test\n1 test failed\n```\n")
+}
+
+func TestReport_FailedCommand(t *testing.T) {
+	result := newTestReportResult(t)
+	test := result.StepResults[2]
+	test.AddCommand(&CommandResult{Command: "npm ci", Phase: PhaseScript})
+	test.AddCommand(&CommandResult{Command: "npm test", Phase: PhaseScript, ExitCode: 2})
+	test.AddCommand(&CommandResult{Command: "npm run report", Phase: PhaseAfterScript, ExitCode: 1})
+
+	report := NewReport(result)
+	assert.Equal(t, "npm test", report.Steps[1].FailedCommand)
+	assert.Empty(t, report.Steps[0].FailedCommand)
+
+	data, err := report.JUnit()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `<failure message="step failed with exit code 2 at command: npm test">`)
+	assert.Contains(t, string(report.Markdown()), "failed with exit code 2\n\nFailed command: `npm test`\n\n```\n")
+}
+
+func TestParseReportTarget(t *testing.T) {
+	target, err := ParseReportTarget("junit=out/report.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, &ReportTarget{Format: ReportJUnit, Path: "out/report.xml"}, target)
+
+	_, err = ParseReportTarget("html")
+	assert.Error(t, err)
+}
@@ -2,33 +2,194 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/zhex/local-bbp/internal/common"
 	"github.com/zhex/local-bbp/internal/models"
+	"github.com/zhex/local-bbp/internal/testreport"
+	"os"
 	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// ResultFile is the name of the file that the result is persisted to in the run folder
+const ResultFile = "result.json"
+
 type Result struct {
-	ID          string
-	EventName   string
-	StepResults map[float32]*StepResult
-	Status      string
-	Runner      *Runner
-	Artifacts   map[string]string
+	ID          string                  `json:"id"`
+	EventName   string                  `json:"pipeline"`
+	StepResults map[float32]*StepResult `json:"-"`
+	Status      Status                  `json:"status"`
+	Runner      *Runner                 `json:"-"`
+	Artifacts   map[string]string       `json:"artifacts"`
+	Project     string                  `json:"project"`
+	Branch      string                  `json:"branch,omitempty"`
+	Commit      string                  `json:"commit,omitempty"`
+	Tag         string                  `json:"tag,omitempty"`
+	// target branch of a pull request pipeline
+	TargetBranch string            `json:"targetBranch,omitempty"`
+	Clean        bool              `json:"clean,omitempty"`
+	Variables    map[string]string `json:"variables,omitempty"`
+	// id of the run that the skipped steps and their artifacts are taken from
+	PreviousRun string `json:"previousRun,omitempty"`
+	// the run is a shell session of the exec command rather than a run of the pipeline
+	Interactive bool      `json:"interactive,omitempty"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+
+	outputDir     string
+	artifactsLock sync.Mutex
+	statusLock    sync.RWMutex
 }
 
 func NewResult(name string, r *Runner) *Result {
-	return &Result{
+	result := &Result{
 		ID:          common.NewID("r-"),
 		EventName:   name,
 		StepResults: make(map[float32]*StepResult),
-		Status:      "pending",
+		Status:      StatusPending,
 		Runner:      r,
 		Artifacts:   make(map[string]string),
 	}
+	if r != nil {
+		result.Project = r.Info.Path
+		result.Branch = r.Info.BranchName
+		result.Commit = r.Info.CommitID
+		result.Tag = r.Info.Tag
+		result.Clean = r.Options.Clean
+		result.Interactive = r.Options.Interactive
+		result.outputDir = r.Config.OutputDir
+	}
+	return result
+}
+
+// SetStatus sets the status of the pipeline while the dashboard may read it
+func (r *Result) SetStatus(status Status) {
+	r.statusLock.Lock()
+	defer r.statusLock.Unlock()
+	r.Status = status
+}
+
+func (r *Result) GetStatus() Status {
+	r.statusLock.RLock()
+	defer r.statusLock.RUnlock()
+	return r.Status
+}
+
+// LoadResult reads the persisted result of the run from the output dir
+func LoadResult(outputDir, id string) (*Result, error) {
+	data, err := os.ReadFile(path.Join(outputDir, id, ResultFile))
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	result.outputDir = outputDir
+	return &result, nil
+}
+
+// ListResults returns the persisted results in the output dir, the latest run first
+func ListResults(outputDir string) ([]*Result, error) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var results []*Result
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "r-") {
+			continue
+		}
+		result, err := LoadResult(outputDir, entry.Name())
+		if err != nil {
+			// runs of older versions have no result file
+			continue
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].StartTime.After(results[j].StartTime)
+	})
+	return results, nil
+}
+
+// Save writes the result to the run folder
+func (r *Result) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(r.GetResultPath(), ResultFile), data, 0644)
+}
+
+type resultJSON Result
+
+// MarshalJSON writes the step results as a list ordered by the step index
+func (r *Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		*resultJSON
+		Steps []*StepResult `json:"steps"`
+	}{(*resultJSON)(r), r.GetSteps()})
+}
+
+func (r *Result) UnmarshalJSON(data []byte) error {
+	v := struct {
+		*resultJSON
+		Steps []*StepResult `json:"steps"`
+	}{resultJSON: (*resultJSON)(r)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.StepResults = make(map[float32]*StepResult)
+	for _, sr := range v.Steps {
+		sr.Result = r
+		r.StepResults[sr.Index] = sr
+	}
+	if r.Artifacts == nil {
+		r.Artifacts = make(map[string]string)
+	}
+	return nil
+}
+
+// AddArtifact records the artifact saved by the step, sr is nil for the artifacts
+// that are not saved by a step
+func (r *Result) AddArtifact(sr *StepResult, id, pattern string) {
+	r.artifactsLock.Lock()
+	defer r.artifactsLock.Unlock()
+	r.Artifacts[id] = pattern
+	if sr != nil {
+		sr.Artifacts = append(sr.Artifacts, id)
+	}
+}
+
+// FindStep returns the step result by the step name or the index string
+func (r *Result) FindStep(s string) *StepResult {
+	for _, sr := range r.GetSteps() {
+		if sr.Name == s || sr.GetIdxString() == s {
+			return sr
+		}
+	}
+	return nil
+}
+
+// GetSteps returns the step results ordered by the step index
+func (r *Result) GetSteps() []*StepResult {
+	steps := make([]*StepResult, 0, len(r.StepResults))
+	for _, sr := range r.StepResults {
+		steps = append(steps, sr)
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].Index < steps[j].Index
+	})
+	return steps
 }
 
 func (r *Result) AddStep(idx float32, name string, step *models.Step) *StepResult {
@@ -38,9 +199,12 @@ func (r *Result) AddStep(idx float32, name string, step *models.Step) *StepResul
 		Name:    name,
 		Step:    step,
 		Outputs: make(map[string]string),
-		Status:  "pending",
+		Status:  StatusPending,
 		Result:  r,
 	}
+	if step != nil {
+		sr.Caches = step.Caches
+	}
 	r.StepResults[idx] = sr
 	return sr
 }
@@ -48,34 +212,185 @@ func (r *Result) AddStep(idx float32, name string, step *models.Step) *StepResul
 func (r *Result) GetDuration() time.Duration {
 	var start, end time.Time
 	for _, sr := range r.StepResults {
-		if !sr.StartTime.IsZero() && (start.IsZero() || sr.StartTime.Before(start)) {
-			start = sr.StartTime
+		srStart, srEnd := sr.getTimes()
+		if !srStart.IsZero() && (start.IsZero() || srStart.Before(start)) {
+			start = srStart
 		}
-		if !sr.EndTime.IsZero() && (end.IsZero() || sr.EndTime.After(end)) {
-			end = sr.EndTime
+		if !srEnd.IsZero() && (end.IsZero() || srEnd.After(end)) {
+			end = srEnd
 		}
 	}
 	return end.Sub(start)
 }
 
+// GetFinalStatus derives the pipeline status from the step results
+func (r *Result) GetFinalStatus() Status {
+	statuses := make(map[Status]bool)
+	for _, sr := range r.StepResults {
+		statuses[sr.GetStatus()] = true
+	}
+	for _, status := range finalStatusPriority {
+		if statuses[status] {
+			return status
+		}
+	}
+	// the steps are left out by a failure that no step records
+	if statuses[StatusNotRun] {
+		return StatusFailed
+	}
+	return StatusSuccess
+}
+
 func (r *Result) GetResultPath() string {
-	return path.Join(r.Runner.Config.OutputDir, r.ID)
+	return path.Join(r.outputDir, r.ID)
 }
 
 func (r *Result) GetCachePath() string {
-	return path.Join(r.Runner.Config.OutputDir, "caches")
+	return path.Join(r.outputDir, "caches")
 }
 
 type StepResult struct {
-	ID        uuid.UUID
-	Index     float32
-	Name      string
-	Step      *models.Step
-	Outputs   map[string]string
-	StartTime time.Time
-	EndTime   time.Time
-	Status    string
-	Result    *Result
+	ID      uuid.UUID         `json:"id"`
+	Index   float32           `json:"index"`
+	Name    string            `json:"name"`
+	Step    *models.Step      `json:"-"`
+	Outputs map[string]string `json:"-"`
+	// name of the stage or "parallel" for the steps of a group
+	Group string `json:"group,omitempty"`
+	// name of the image that the step runs in
+	Image     string    `json:"image,omitempty"`
+	Caches    []string  `json:"caches,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Status    Status    `json:"status"`
+	// exit code of the failed command of the script
+	ExitCode int `json:"exitCode"`
+	// ids of the artifacts saved by the step
+	Artifacts []string `json:"artifacts,omitempty"`
+	// summary of the test reports found after the script
+	Tests *testreport.Summary `json:"tests,omitempty"`
+	// timings of the phases that the step ran, in the order they ran
+	Phases []*PhaseResult `json:"phases,omitempty"`
+	// commands of the script and the after-script that started, in the order they ran
+	Commands []*CommandResult `json:"commands,omitempty"`
+	Result   *Result          `json:"-"`
+
+	phase string
+	// guards the phase, the status and the times, which the dashboard reads
+	// while the step runs
+	lock sync.RWMutex
+}
+
+// SetStatus sets the status of the step while it may be running
+func (sr *StepResult) SetStatus(status Status) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.Status = status
+}
+
+func (sr *StepResult) GetStatus() Status {
+	sr.lock.RLock()
+	defer sr.lock.RUnlock()
+	return sr.Status
+}
+
+func (sr *StepResult) SetStartTime(t time.Time) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.StartTime = t
+}
+
+func (sr *StepResult) SetEndTime(t time.Time) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.EndTime = t
+}
+
+func (sr *StepResult) getTimes() (time.Time, time.Time) {
+	sr.lock.RLock()
+	defer sr.lock.RUnlock()
+	return sr.StartTime, sr.EndTime
+}
+
+// SetPhase records the phase that the step is currently running
+func (sr *StepResult) SetPhase(phase string) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.phase = phase
+}
+
+func (sr *StepResult) GetPhase() string {
+	sr.lock.RLock()
+	defer sr.lock.RUnlock()
+	return sr.phase
+}
+
+// AddPhase records the timing of a phase that the step ran
+func (sr *StepResult) AddPhase(phase *PhaseResult) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.Phases = append(sr.Phases, phase)
+}
+
+// AddCommand records a command of the step when it starts
+func (sr *StepResult) AddCommand(c *CommandResult) {
+	sr.Commands = append(sr.Commands, c)
+}
+
+// GetFailedCommand returns the command of the script that failed the step, nil
+// if there is none
+func (sr *StepResult) GetFailedCommand() *CommandResult {
+	for i := len(sr.Commands) - 1; i >= 0; i-- {
+		c := sr.Commands[i]
+		if c.Phase == PhaseScript && c.ExitCode != 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+// GetFailedCommandMessage returns the line that points out the command that
+// failed the step, empty if there is none
+func (sr *StepResult) GetFailedCommandMessage() string {
+	c := sr.GetFailedCommand()
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("Step [%s] %s failed at command: %s (exit code %d after %s)", sr.GetIdxString(), sr.Name, c.GetTitle(), c.ExitCode, c.GetDuration().Round(time.Millisecond))
+}
+
+// GetPhaseDurations returns the time spent in each phase of the step
+func (sr *StepResult) GetPhaseDurations() map[string]time.Duration {
+	sr.lock.RLock()
+	defer sr.lock.RUnlock()
+	durations := make(map[string]time.Duration)
+	for _, p := range sr.Phases {
+		durations[p.Name] += p.GetDuration()
+	}
+	return durations
+}
+
+// IsRunning reports whether the step has started and not ended yet
+func (sr *StepResult) IsRunning() bool {
+	start, end := sr.getTimes()
+	return !start.IsZero() && end.IsZero()
+}
+
+// GetDuration returns the elapsed time of the step, up to now if it is still running
+func (sr *StepResult) GetDuration() time.Duration {
+	start, end := sr.getTimes()
+	if start.IsZero() {
+		return 0
+	}
+	if end.IsZero() {
+		return time.Since(start)
+	}
+	return end.Sub(start)
+}
+
+func (sr *StepResult) GetLogPath() string {
+	name := common.SanitizeFileName(fmt.Sprintf("%s-%s.log", sr.GetIdxString(), sr.Name))
+	return path.Join(sr.Result.GetResultPath(), "logs", name)
 }
 
 func (sr *StepResult) GetIdxString() string {
@@ -2,6 +2,9 @@ package runner
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/zhex/local-bbp/internal/config"
+	"github.com/zhex/local-bbp/internal/models"
+	"os"
 	"testing"
 )
 
@@ -17,3 +20,63 @@ func TestStepResult_GetIdxString(t *testing.T) {
 	sr.Index = 1.22
 	assert.Equal(t, "1.22", sr.GetIdxString())
 }
+
+func TestResult_GetFinalStatus(t *testing.T) {
+	r := NewResult("default", nil)
+	r.AddStep(1, "build", nil).Status = StatusSuccess
+	assert.Equal(t, StatusSuccess, r.GetFinalStatus())
+
+	r.AddStep(2, "lint", nil).Status = StatusSkipped
+	assert.Equal(t, StatusSuccess, r.GetFinalStatus())
+
+	r.AddStep(3, "deploy", nil).Status = StatusPaused
+	assert.Equal(t, StatusPaused, r.GetFinalStatus())
+
+	r.AddStep(4, "e2e", nil).Status = StatusTimeout
+	assert.Equal(t, StatusTimeout, r.GetFinalStatus())
+
+	r.AddStep(5, "test", nil).Status = StatusFailed
+	r.AddStep(6, "release", nil).Status = StatusNotRun
+	assert.Equal(t, StatusFailed, r.GetFinalStatus())
+
+	// a step fails without recording it, the later steps are not run
+	r = NewResult("default", nil)
+	r.AddStep(1, "build", nil).Status = StatusSuccess
+	r.AddStep(2, "test", nil).Status = StatusNotRun
+	assert.Equal(t, StatusFailed, r.GetFinalStatus())
+}
+
+func TestLoadResult(t *testing.T) {
+	dir := t.TempDir()
+	r := New(dir, &config.Config{OutputDir: dir}, nil)
+	result := NewResult("default", r)
+	result.Status = StatusFailed
+	result.Artifacts["a1"] = "dist/**"
+	build := result.AddStep(1, "build", &models.Step{Caches: []string{"node"}})
+	build.Status = StatusSuccess
+	test := result.AddStep(2.1, "test", nil)
+	test.Status = StatusFailed
+	test.ExitCode = 2
+
+	assert.NoError(t, os.MkdirAll(result.GetResultPath(), 0755))
+	assert.NoError(t, result.Save())
+
+	loaded, err := LoadResult(dir, result.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "default", loaded.EventName)
+	assert.Equal(t, StatusFailed, loaded.Status)
+	assert.Equal(t, map[string]string{"a1": "dist/**"}, loaded.Artifacts)
+	assert.Equal(t, result.GetResultPath(), loaded.GetResultPath())
+
+	steps := loaded.GetSteps()
+	assert.Len(t, steps, 2)
+	assert.Equal(t, "build", steps[0].Name)
+	assert.Equal(t, []string{"node"}, steps[0].Caches)
+	assert.Equal(t, float32(2.1), steps[1].Index)
+	assert.Equal(t, 2, steps[1].ExitCode)
+	assert.Equal(t, loaded, steps[1].Result)
+
+	results, err := ListResults(dir)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
@@ -15,6 +15,8 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,6 +26,17 @@ type Runner struct {
 	Info       *ProjectInfo
 	Secrets    map[string]string
 	CacheStore *cache.Store
+	Options    *Options
+	// resolved values of the custom pipeline variables
+	Variables map[string]string
+
+	changedFilesOnce sync.Once
+	changedFiles     []string
+	changedFilesErr  error
+
+	// networks of the running steps, removed on force quit
+	networks     []*docker.Network
+	networksLock sync.Mutex
 }
 
 func New(projPath string, conf *config.Config, secrets map[string]string) *Runner {
@@ -31,6 +44,10 @@ func New(projPath string, conf *config.Config, secrets map[string]string) *Runne
 		Config:  conf,
 		Info:    NewProjInfo(projPath),
 		Secrets: secrets,
+		Options: &Options{
+			Output:             OutputStream,
+			UncommittedChanges: true,
+		},
 	}
 }
 
@@ -61,8 +78,64 @@ func (r *Runner) LoadPlan() error {
 	return nil
 }
 
+// startDashboard starts the terminal dashboard of the result and writes the
+// runner logs to a file, the plain output is used if stdout is not a terminal
+func (r *Runner) startDashboard(result *Result, logger log.FieldLogger) *Dashboard {
+	if !IsDashboardSupported() {
+		logger.Warn("stdout is not a terminal, fall back to the plain output")
+		r.Options.TUI = false
+		return nil
+	}
+	if !r.Options.AutoApprove && !r.Options.StopAtManual {
+		logger.Warn("manual steps can not be approved in the dashboard, the pipeline will pause at the first manual step")
+		r.Options.StopAtManual = true
+	}
+	r.Options.Output = OutputFile
+
+	logFile, err := os.OpenFile(path.Join(result.GetResultPath(), "runner.log"), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		logger.Fatalf("Error creating runner log file: %s", err)
+	}
+	log.SetOutput(logFile)
+
+	dashboard := NewDashboard(result)
+	if err := dashboard.Start(); err != nil {
+		log.SetOutput(os.Stderr)
+		logger.Fatalf("Error starting dashboard: %s", err)
+	}
+	return dashboard
+}
+
+// ResolvePipeline returns the name of the pipeline that Bitbucket runs for the
+// tag, the pull request or the branch of the project info, in this order
+func (r *Runner) ResolvePipeline() (string, error) {
+	if r.Plan == nil {
+		if err := r.LoadPlan(); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case r.Info.Tag != "":
+		if name := r.Plan.FindTagPipeline(r.Info.Tag); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("no pipeline matches the tag %s", r.Info.Tag)
+	case r.Info.DestinationBranch != "":
+		if name := r.Plan.FindPullRequestPipeline(r.Info.BranchName); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("no pipeline matches the pull request from %s", r.Info.BranchName)
+	default:
+		if name := r.Plan.FindBranchPipeline(r.Info.BranchName); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("no pipeline matches the branch %s", r.Info.BranchName)
+	}
+}
+
 func (r *Runner) Run(name string, targetBranch string) {
-	ctx := context.Background()
+	ctx, stop := r.handleSignals(context.Background())
+	defer stop()
 
 	if r.Plan == nil {
 		if err := r.LoadPlan(); err != nil {
@@ -79,11 +152,28 @@ func (r *Runner) Run(name string, targetBranch string) {
 	})
 	ctx = WithLogger(ctx, logger)
 
+	// only print warnings and the pipeline result in quiet mode
+	logLevel := log.GetLevel()
+	if r.Options.Output == OutputQuiet {
+		log.SetLevel(log.WarnLevel)
+	}
+
 	actions := r.Plan.GetPipeline(name)
 	if actions == nil {
 		logger.Fatalf("No pipeline [%s] found", name)
 	}
 
+	variables, err := r.resolveVariables(ctx, name)
+	if errors.Is(err, context.Canceled) {
+		logger.Fatal("Pipeline stopped")
+	}
+	if err != nil {
+		logger.Fatalf("Error resolving variables: %s", err)
+	}
+	r.Variables = variables
+	result.Variables = variables
+	result.TargetBranch = targetBranch
+
 	if err := os.MkdirAll(fmt.Sprintf("%s/logs", result.GetResultPath()), 0755); err != nil {
 		logger.Fatalf("Error creating output directory: %s", err)
 	}
@@ -113,26 +203,81 @@ func (r *Runner) Run(name string, targetBranch string) {
 		}
 	}
 
+	if err := r.applyStepFilter(result); err != nil {
+		logger.Fatalf("Error selecting steps: %s", err)
+	}
+
+	if err := r.addArtifactsDir(result); err != nil {
+		logger.Fatalf("Error copying artifacts directory: %s", err)
+	}
+
 	if chain != nil {
-		chain = WithTimeout(chain, time.Duration(r.Config.MaxPipelineTimeout)*time.Minute)
+		// the lock tells the other commands that the run is in progress
+		unlock, err := LockRun(r.Config.OutputDir, result.ID)
+		if err != nil {
+			logger.Fatalf("Error locking run: %s", err)
+		}
+
+		result.StartTime = time.Now()
+		if err := result.Save(); err != nil {
+			logger.Warnf("Error saving result: %s", err)
+		}
+
+		var dashboard *Dashboard
+		if r.Options.TUI {
+			dashboard = r.startDashboard(result, logger)
+		}
+
+		// the timeout is on the context of the whole chain, so the steps left
+		// pending by the timeout are marked in the finally
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(r.Config.MaxPipelineTimeout)*time.Minute)
+		defer cancel()
 		chain = chain.Finally(func(ctx context.Context) error {
+			// the steps left pending never started
 			for _, sr := range result.StepResults {
-				if sr.Status == "failed" {
-					result.Status = "failed"
-					break
+				if sr.GetStatus() == StatusPending {
+					sr.SetStatus(getNotStartedStatus(ctx))
 				}
 			}
-			if result.Status == "pending" {
-				result.Status = "success"
+			result.SetStatus(result.GetFinalStatus())
+			result.EndTime = time.Now()
+			if err := result.Save(); err != nil {
+				logger.Warnf("Error saving result: %s", err)
+			}
+			unlock()
+			reports, err := r.writeReports(result)
+			if err != nil {
+				logger.Warnf("Error writing report: %s", err)
+			}
+			if dashboard != nil {
+				dashboard.Stop()
+				log.SetOutput(os.Stderr)
 			}
+			log.SetLevel(logLevel)
 			fmt.Print("\n\n")
-			logger.Println("Pipeline result: ", getColoredStatus(result.Status))
+			logger.Println("Pipeline result: ", GetColoredStatus(result.Status))
+			for _, line := range strings.Split(RenderStepSummary(result), "\n") {
+				logger.Println(line)
+			}
+			for _, sr := range result.GetSteps() {
+				if msg := sr.GetFailedCommandMessage(); msg != "" {
+					logger.Println(common.ColorRed(msg))
+				}
+			}
 			logger.Println("Total Elapsed Time:", result.GetDuration().Round(time.Millisecond).String())
 			logger.Println("Output Path:", result.GetResultPath())
+			for _, report := range reports {
+				logger.Println("Report:", report)
+			}
 			return nil
 		})
 		logger.Infof("Start pipeline: %s", result.EventName)
-		if err := chain(ctx); err != nil {
+		err = chain(ctx)
+		// the run is cancelled, or a manual step is aborted
+		if errors.Is(err, context.Canceled) || errors.Is(err, ErrPipelineStopped) {
+			logger.Fatal("Pipeline stopped")
+		}
+		if err != nil && !errors.Is(err, ErrPipelinePaused) {
 			logger.Fatalf("Error running task: %s", err)
 		}
 	}
@@ -143,8 +288,12 @@ func (r *Runner) newParallelTask(parallel *models.Parallel, i int, result *Resul
 	for j, subAction := range parallel.Actions {
 		idx := float32(i+1) + float32(j+1)/10
 		sr := result.AddStep(idx, subAction.Step.GetName(), subAction.Step)
+		sr.Group = "parallel"
 		parallelTasks = append(parallelTasks, r.newStepTask(sr, targetBranch))
 	}
+	if parallel.FailFast {
+		return FailFastParallelTask(r.getParallelSize(), parallelTasks...)
+	}
 	return ParallelTask(r.getParallelSize(), parallelTasks...)
 }
 
@@ -152,22 +301,47 @@ func (r *Runner) newStageTask(stage *models.Stage, i int, result *Result, target
 	// no parallel stages
 	// no parallel steps in stage
 	var stageTasks []Task
+	var stageSteps []*StepResult
+	var firstStep *StepResult
+	group := stage.Name
+	if group == "" {
+		group = "stage"
+	}
 	for j, subAction := range stage.Actions {
 		idx := float32(i+1) + float32(j+1)/10
 		sr := result.AddStep(idx, subAction.Step.GetName(), subAction.Step)
+		sr.Group = group
+		stageSteps = append(stageSteps, sr)
+		if firstStep == nil {
+			firstStep = sr
+		}
 		stageTasks = append(stageTasks, r.newStepTask(sr, targetBranch))
 	}
 
 	t := ChainTask(stageTasks...)
+	if stage.IsManual() && firstStep != nil {
+		t = WithManualTrigger(t, firstStep).Then(func(ctx context.Context) error {
+			// skipping the manual stage skips all its steps
+			for _, sr := range stageSteps {
+				if sr.GetStatus() == StatusPending {
+					sr.SetStatus(StatusSkipped)
+				}
+			}
+			return nil
+		})
+	}
 
-	return t.WithCondition(func() bool {
-		changedFiles, err := common.GetGitChangedFiles(r.Info.Path, targetBranch)
+	return t.WithCondition(skipUnless(func() bool {
+		if stage.Condition == nil {
+			return true
+		}
+		changedFiles, err := r.getChangedFiles(targetBranch)
 		if err != nil {
 			log.Warnf("Error getting git diff files: %s", err)
 			return false
 		}
 		return stage.MatchCondition(changedFiles)
-	})
+	}, stageSteps...))
 }
 
 func (r *Runner) newStepTask(sr *StepResult, targetBranch string) Task {
@@ -181,7 +355,7 @@ func (r *Runner) newStepTask(sr *StepResult, targetBranch string) Task {
 		image = sr.Step.Image
 	}
 
-	envs := common.MergeMaps(r.getEnvs(sr), r.Secrets)
+	envs := common.MergeMaps(r.getEnvs(sr), r.Secrets, r.Variables)
 	c := docker.NewContainer(
 		&docker.Input{
 			Name:         fmt.Sprintf("bbp-%s-%s", sr.Result.ID, sr.GetIdxString()),
@@ -191,64 +365,158 @@ func (r *Runner) newStepTask(sr *StepResult, targetBranch string) Task {
 			WorkDir:      r.Config.WorkDir,
 			Envs:         envs,
 			Entrypoint:   []string{"/bin/sh"},
+			Labels:       docker.NewLabels(r.Info.Path, sr.Result.ID),
 		},
 	)
 	image = NewFieldUpdater(envs).UpdateImage(image)
+	sr.Image = image.Name
+
+	tasks := []Task{
+		WithPhase(NewImagePullTask(c), sr, PhasePull),
+		WithPhase(ChainTask(
+			NewContainerCreateTask(c, sr),
+			NewCreateServicesTask(c, sr),
+			NewContainerStartTask(c),
+		), sr, PhaseSetup),
+		WithPhase(NewCloneTask(c, sr), sr, PhaseClone),
+		WithPhase(NewCachesRestoreTask(c, sr), sr, PhaseCacheRestore),
+		WithPhase(NewDownloadArtifactsTask(c, sr), sr, PhaseArtifactsDownload),
+	}
+	if r.Options.Interactive {
+		// nothing is saved from the shell
+		tasks = append(tasks, WithPhase(NewExecShellTask(c, sr), sr, PhaseShell))
+	} else {
+		tasks = append(tasks,
+			WithPhase(NewScriptTask(c, sr, sr.Step.Script), sr, PhaseScript),
+			WithPhase(NewSaveArtifactsTask(c, sr), sr, PhaseArtifactsSave),
+			WithPhase(NewCachesSaveTask(c, sr), sr, PhaseCacheSave),
+		)
+	}
+	t := ChainTask(tasks...)
+	if !r.Options.Interactive {
+		// the test reports matter the most when the script fails or times out
+		t = t.Finally(WithPhase(WithGracePeriod(NewTestReportsTask(c, sr), gracePeriod), sr, PhaseTestReports))
+	}
 
-	t := ChainTask(
-		NewImagePullTask(c),
-		NewContainerCreateTask(c, sr),
-		NewCreateServicesTask(c, sr),
-		NewContainerStartTask(c),
-		NewCloneTask(c),
-		NewCachesRestoreTask(c, sr),
-		NewDownloadArtifactsTask(c, sr),
-		NewScriptTask(c, sr, sr.Step.Script),
-		NewSaveArtifactsTask(c, sr),
-		NewCachesSaveTask(c, sr),
-	)
+	if r.Options.DebugOnFailure {
+		t = t.Finally(WithPhase(NewDebugShellTask(c, sr), sr, PhaseDebug))
+	}
 
-	if len(sr.Step.AfterScript) > 0 {
-		t = t.Finally(NewCmdTask(c, sr, sr.Step.AfterScript))
+	if len(sr.Step.AfterScript) > 0 && !r.Options.Interactive {
+		t = t.Finally(WithPhase(WithGracePeriod(NewAfterScriptTask(c, sr), gracePeriod), sr, PhaseAfterScript))
 	}
 
-	t = t.Finally(NewContainerDestroyTask(c))
+	t = t.Finally(WithPhase(WithGracePeriod(NewContainerDestroyTask(c), gracePeriod), sr, PhaseTeardown))
 
-	timeout := sr.Result.Runner.Config.MaxStepTimeout
-	if sr.Step.MaxTime > 0 {
-		timeout = sr.Step.MaxTime
+	timeout := r.Plan.GetStepMaxTime(sr.Step, r.Config.MaxStepTimeout)
+
+	t = WithTimeout(t, time.Duration(timeout)*time.Minute)
+	if sr.Step.IsManual() && !r.Options.Interactive {
+		t = WithManualTrigger(t, sr)
 	}
 
-	t = WithTimeout(t, time.Duration(timeout)*time.Minute).
-		WithCondition(func() bool {
-			changedFiles, err := common.GetGitChangedFiles(r.Info.Path, targetBranch)
-			if err != nil {
-				log.Warnf("Error getting git diff files: %s", err)
-				return false
-			}
-			return sr.Step.MatchCondition(changedFiles)
-		})
+	t = t.WithCondition(skipUnless(func() bool {
+		if r.Options.Interactive {
+			return true
+		}
+		if sr.Step.Condition == nil {
+			return true
+		}
+		changedFiles, err := r.getChangedFiles(targetBranch)
+		if err != nil {
+			log.Warnf("Error getting git diff files: %s", err)
+			return false
+		}
+		return sr.Step.MatchCondition(changedFiles)
+	}, sr))
 
 	return func(ctx context.Context) error {
 		ctx = WithLoggerComposeStepResult(ctx, sr)
 		logger := GetLogger(ctx)
-		logger.Infof("Start step: %s", sr.Name)
 		result := GetResult(ctx)
 		stepResult, _ := result.StepResults[sr.Index]
-		stepResult.StartTime = time.Now()
+
+		if stepResult.GetStatus() == StatusSkipped {
+			logger.Infof("Step skipped: %s", sr.Name)
+			return nil
+		}
+
+		// the step is still queued when the run, or a fail-fast sibling, cancels the context
+		if err := ctx.Err(); err != nil {
+			stepResult.SetStatus(getNotStartedStatus(ctx))
+			logger.Infof("Step %s: %s", stepResult.GetStatus(), sr.Name)
+			return err
+		}
+
+		logger.Infof("Start step: %s", sr.Name)
+		stepResult.SetStartTime(time.Now())
 
 		err := t(ctx)
-		if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		stepResult.SetEndTime(time.Now())
+		stepResult.SetPhase("")
+		stepResult.SetStatus(getEndStatus(stepResult.GetStatus(), err))
+		if stepResult.GetStatus() == StatusTimeout {
 			logger.Info("Step timeout")
 		}
 
-		d := stepResult.EndTime.Sub(stepResult.StartTime)
-		logger.Infof("End step: %s [%s] %s", sr.Name, getColoredStatus(stepResult.Status), common.ColorGrey(d.Round(time.Millisecond).String()))
+		d := stepResult.GetDuration()
+		logger.Infof("End step: %s [%s] %s", sr.Name, GetColoredStatus(stepResult.GetStatus()), common.ColorGrey(d.Round(time.Millisecond).String()))
 
 		return err
 	}
 }
 
+// getNotStartedStatus returns the status of a step that never starts, from the
+// cause of the done context: the run timed out, or it was stopped or a
+// fail-fast sibling failed. The step is not run if the context is not done,
+// e.g. when a previous step failed.
+func getNotStartedStatus(ctx context.Context) Status {
+	switch {
+	case errors.Is(context.Cause(ctx), context.DeadlineExceeded):
+		return StatusTimeout
+	case ctx.Err() != nil:
+		return StatusStopped
+	}
+	return StatusNotRun
+}
+
+// getEndStatus returns the status of the step from the status set by its tasks
+// and the error of the step. A step that returns an error fails even when its
+// script succeeded, e.g. when saving the artifacts or in the after-script.
+func getEndStatus(status Status, err error) Status {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || status == StatusTimeout:
+		return StatusTimeout
+	case errors.Is(err, context.Canceled) && status != StatusPaused:
+		return StatusStopped
+	case err != nil && (status == StatusPending || status == StatusSuccess):
+		return StatusFailed
+	}
+	return status
+}
+
+// skipUnless marks the steps skipped when the condition is not met
+func skipUnless(cond func() bool, steps ...*StepResult) func() bool {
+	return func() bool {
+		if cond() {
+			return true
+		}
+		for _, sr := range steps {
+			sr.SetStatus(StatusSkipped)
+		}
+		return false
+	}
+}
+
+// getChangedFiles resolves the changed files of the pipeline once for all the conditions
+func (r *Runner) getChangedFiles(targetBranch string) ([]string, error) {
+	r.changedFilesOnce.Do(func() {
+		includeWorkingTree := r.Options.UncommittedChanges && !r.Options.Clean
+		r.changedFiles, r.changedFilesErr = common.GetGitChangedFiles(r.Info.Path, r.Info.CommitID, targetBranch, includeWorkingTree)
+	})
+	return r.changedFiles, r.changedFilesErr
+}
+
 func (r *Runner) getParallelSize() int {
 	ncpu := runtime.NumCPU()
 	if 1 > ncpu {
@@ -258,7 +526,7 @@ func (r *Runner) getParallelSize() int {
 }
 
 func (r *Runner) getEnvs(sr *StepResult) map[string]string {
-	return map[string]string{
+	envs := map[string]string{
 		"BITBUCKET_BUILD_NUMBER":        sr.Result.ID,
 		"BITBUCKET_BRANCH":              r.Info.BranchName,
 		"BITBUCKET_CLONE_DIR":           r.Config.WorkDir,
@@ -283,15 +551,13 @@ func (r *Runner) getEnvs(sr *StepResult) map[string]string {
 		"DOCKER_HOST":                   "unix:///var/run/docker.sock",
 		"PIPELINES_JWT_TOKEN":           "PIPELINES_JWT_TOKEN",
 	}
-}
-
-func getColoredStatus(status string) string {
-	switch status {
-	case "success":
-		return common.ColorGreen(status)
-	case "failed":
-		return common.ColorRed(status)
-	default:
-		return common.ColorGrey(status)
+	if r.Info.Tag != "" {
+		envs["BITBUCKET_TAG"] = r.Info.Tag
+		delete(envs, "BITBUCKET_BRANCH")
+	}
+	if r.Info.DestinationBranch != "" {
+		envs["BITBUCKET_PR_ID"] = "1"
+		envs["BITBUCKET_PR_DESTINATION_BRANCH"] = r.Info.DestinationBranch
 	}
+	return envs
 }
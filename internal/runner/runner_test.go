@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGetEndStatus(t *testing.T) {
+	errTask := errors.New("task failed")
+	assert.Equal(t, StatusSuccess, getEndStatus(StatusSuccess, nil))
+	// the artifacts or the after-script fail after the script
+	assert.Equal(t, StatusFailed, getEndStatus(StatusSuccess, errTask))
+	// the image pull fails before the script
+	assert.Equal(t, StatusFailed, getEndStatus(StatusPending, errTask))
+	assert.Equal(t, StatusFailed, getEndStatus(StatusFailed, errTask))
+	assert.Equal(t, StatusTimeout, getEndStatus(StatusSuccess, context.DeadlineExceeded))
+	assert.Equal(t, StatusStopped, getEndStatus(StatusPending, context.Canceled))
+	assert.Equal(t, StatusPaused, getEndStatus(StatusPaused, ErrPipelinePaused))
+	assert.Equal(t, StatusStopped, getEndStatus(StatusStopped, ErrPipelineStopped))
+}
+
+func TestGetNotStartedStatus(t *testing.T) {
+	assert.Equal(t, StatusNotRun, getNotStartedStatus(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, StatusStopped, getNotStartedStatus(ctx))
+
+	// the queued siblings of a failed fail-fast step
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(ErrFailFast)
+	assert.Equal(t, StatusStopped, getNotStartedStatus(ctx))
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.Equal(t, StatusTimeout, getNotStartedStatus(ctx))
+}
@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"errors"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/google/uuid"
+	"github.com/zhex/local-bbp/internal/common"
+	"path"
+	"strings"
+)
+
+// StepSelection selects the steps to run by the step name glob or the index string
+type StepSelection struct {
+	Only  []string
+	Skip  []string
+	From  string
+	Until string
+}
+
+func (s *StepSelection) IsEmpty() bool {
+	return len(s.Only) == 0 && len(s.Skip) == 0 && s.From == "" && s.Until == ""
+}
+
+// Match reports whether the step is selected, it is used as the step filter of the runner
+func (s *StepSelection) Match(sr *StepResult) bool {
+	if len(s.Only) > 0 && !matchStepPatterns(sr, s.Only) {
+		return false
+	}
+	if matchStepPatterns(sr, s.Skip) {
+		return false
+	}
+	if s.From != "" {
+		from := findMatchedStep(sr.Result, s.From, false)
+		if from == nil || sr.Index < from.Index {
+			return false
+		}
+	}
+	if s.Until != "" {
+		// the whole group runs until its index
+		until := findMatchedStep(sr.Result, s.Until, true)
+		if until == nil || sr.Index > until.Index {
+			return false
+		}
+	}
+	return true
+}
+
+func matchStepPatterns(sr *StepResult, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchStep(sr, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStep matches the step by the index, where the index of a group also matches
+// its steps, or by the name glob
+func matchStep(sr *StepResult, pattern string) bool {
+	idx := sr.GetIdxString()
+	if pattern == idx || strings.HasPrefix(idx, pattern+".") {
+		return true
+	}
+	ok, _ := doublestar.Match(pattern, sr.Name)
+	return ok
+}
+
+// findMatchedStep returns the first step of the result that matches the
+// pattern, or the last one if last is true
+func findMatchedStep(result *Result, pattern string, last bool) *StepResult {
+	var matched *StepResult
+	for _, sr := range result.GetSteps() {
+		if !matchStep(sr, pattern) {
+			continue
+		}
+		if !last {
+			return sr
+		}
+		matched = sr
+	}
+	return matched
+}
+
+// applyStepFilter marks the steps that are not selected as skipped, and takes
+// the artifacts of the skipped steps before the last selected step from the previous run
+func (r *Runner) applyStepFilter(result *Result) error {
+	if r.Options.StepFilter == nil {
+		return nil
+	}
+	var skipped []*StepResult
+	var last *StepResult
+	for _, sr := range result.GetSteps() {
+		if r.Options.StepFilter(sr) {
+			last = sr
+			continue
+		}
+		sr.SetStatus(StatusSkipped)
+		skipped = append(skipped, sr)
+	}
+	if last == nil {
+		return errors.New("no step is selected to run")
+	}
+
+	prev := r.Options.PreviousResult
+	if prev == nil {
+		return nil
+	}
+	result.PreviousRun = prev.ID
+	for _, sr := range skipped {
+		psr := prev.StepResults[sr.Index]
+		if sr.Index > last.Index || psr == nil || psr.Name != sr.Name {
+			continue
+		}
+		// artifacts saved by the step, or taken from an earlier run
+		for _, id := range psr.Artifacts {
+			src := path.Join(prev.GetResultPath(), "artifacts", id)
+			dst := path.Join(result.GetResultPath(), "artifacts", id)
+			if err := common.CopyDir(src, dst); err != nil {
+				return err
+			}
+			result.AddArtifact(sr, id, prev.Artifacts[id])
+		}
+	}
+	return nil
+}
+
+// addArtifactsDir adds the artifacts directory of the options as an artifact,
+// which is downloaded into the steps like the artifacts of earlier steps
+func (r *Runner) addArtifactsDir(result *Result) error {
+	if r.Options.ArtifactsDir == "" {
+		return nil
+	}
+	id := uuid.New().String()
+	if err := common.CopyDir(r.Options.ArtifactsDir, path.Join(result.GetResultPath(), "artifacts", id)); err != nil {
+		return err
+	}
+	result.AddArtifact(nil, id, r.Options.ArtifactsDir)
+	return nil
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zhex/local-bbp/internal/config"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRunner_applyStepFilter(t *testing.T) {
+	dir := t.TempDir()
+	r := New(dir, &config.Config{OutputDir: dir}, nil)
+
+	prev := NewResult("default", r)
+	prev.ID = "r-1"
+	build := prev.AddStep(1, "build", nil)
+	build.Status = StatusSuccess
+	prev.AddArtifact(build, "a1", "dist/**")
+	prev.AddStep(2, "deploy", nil).Status = StatusFailed
+	_ = os.MkdirAll(path.Join(prev.GetResultPath(), "artifacts", "a1", "dist"), 0755)
+	_ = os.WriteFile(path.Join(prev.GetResultPath(), "artifacts", "a1", "dist", "app.js"), []byte("app"), 0644)
+
+	r.Options.PreviousResult = prev
+	r.Options.StepFilter = func(sr *StepResult) bool {
+		return sr.Index >= 2
+	}
+
+	result := NewResult("default", r)
+	result.ID = "r-2"
+	result.AddStep(1, "build", nil)
+	result.AddStep(2, "deploy", nil)
+	assert.NoError(t, r.applyStepFilter(result))
+
+	assert.Equal(t, "r-1", result.PreviousRun)
+	assert.Equal(t, StatusSkipped, result.StepResults[1].Status)
+	assert.Equal(t, []string{"a1"}, result.StepResults[1].Artifacts)
+	assert.Equal(t, StatusPending, result.StepResults[2].Status)
+	assert.Equal(t, map[string]string{"a1": "dist/**"}, result.Artifacts)
+	assert.FileExists(t, path.Join(result.GetResultPath(), "artifacts", "a1", "dist", "app.js"))
+}
+
+func TestStepSelection_Match(t *testing.T) {
+	result := NewResult("default", nil)
+	build := result.AddStep(1, "build", nil)
+	lint := result.AddStep(2.1, "lint", nil)
+	test := result.AddStep(2.2, "unit test", nil)
+	deploy := result.AddStep(3, "deploy/staging", nil)
+
+	selected := func(s *StepSelection) []string {
+		var names []string
+		for _, sr := range []*StepResult{build, lint, test, deploy} {
+			if s.Match(sr) {
+				names = append(names, sr.Name)
+			}
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"build", "lint", "unit test", "deploy/staging"}, selected(&StepSelection{}))
+	assert.Equal(t, []string{"deploy/staging"}, selected(&StepSelection{Only: []string{"deploy/*"}}))
+	assert.Equal(t, []string{"lint", "unit test"}, selected(&StepSelection{Only: []string{"2"}}))
+	assert.Equal(t, []string{"build", "unit test"}, selected(&StepSelection{Only: []string{"build", "2.2"}}))
+	assert.Equal(t, []string{"build", "deploy/staging"}, selected(&StepSelection{Skip: []string{"2"}}))
+	assert.Equal(t, []string{"unit test", "deploy/staging"}, selected(&StepSelection{From: "unit*"}))
+	assert.Equal(t, []string{"build", "lint"}, selected(&StepSelection{Until: "2.1"}))
+	assert.Equal(t, []string{"build", "lint", "unit test"}, selected(&StepSelection{Until: "2"}))
+	assert.Equal(t, []string{"lint"}, selected(&StepSelection{From: "2", Until: "lint"}))
+	assert.Nil(t, selected(&StepSelection{From: "unknown"}))
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"github.com/zhex/local-bbp/internal/docker"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// gracePeriod is the time given to the test reports, the after-scripts and the
+// teardown once the step is cancelled
+const gracePeriod = 30 * time.Second
+
+// handleSignals cancels the context on the first interrupt to stop the pipeline
+// gracefully. On the second interrupt, the containers are removed right away
+// and the process exits.
+func (r *Runner) handleSignals(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-sigs:
+		case <-done:
+			return
+		}
+		log.Warn("Interrupted, stopping the pipeline. Press Ctrl-C again to force quit")
+		cancel()
+
+		select {
+		case <-sigs:
+		case <-done:
+			return
+		}
+		log.Warn("Force quit, removing the containers")
+		r.destroyNetworks()
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel()
+	}
+}
+
+func (r *Runner) addNetwork(net *docker.Network) {
+	r.networksLock.Lock()
+	defer r.networksLock.Unlock()
+	r.networks = append(r.networks, net)
+}
+
+func (r *Runner) removeNetwork(net *docker.Network) {
+	r.networksLock.Lock()
+	defer r.networksLock.Unlock()
+	for i, n := range r.networks {
+		if n == net {
+			r.networks = append(r.networks[:i], r.networks[i+1:]...)
+			return
+		}
+	}
+}
+
+// destroyNetworks removes the networks of the running steps with their containers
+func (r *Runner) destroyNetworks() {
+	r.networksLock.Lock()
+	defer r.networksLock.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, net := range r.networks {
+		if err := net.Destroy(ctx); err != nil {
+			log.Errorf("Error removing network %s: %s", net.Name, err)
+		}
+	}
+	r.networks = nil
+}
+
+// WithGracePeriod keeps running the task for the grace period after the
+// context is cancelled, so it can clean up after a stopped or timed out step
+func WithGracePeriod(task Task, grace time.Duration) Task {
+	return func(ctx context.Context) error {
+		taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		stop := context.AfterFunc(ctx, func() {
+			time.AfterFunc(grace, cancel)
+		})
+		defer stop()
+		return task(taskCtx)
+	}
+}
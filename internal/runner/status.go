@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"github.com/zhex/local-bbp/internal/common"
+	"strings"
+)
+
+// Status is the status of a step or of the pipeline
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	// the step condition is not met, or the step is left out of the run
+	StatusSkipped Status = "skipped"
+	// the run is cancelled while the step runs or waits for approval
+	StatusStopped Status = "stopped"
+	// the step exceeds its max-time
+	StatusTimeout Status = "timeout"
+	// the manual step waits for approval
+	StatusPaused Status = "paused"
+	// the step never starts because a previous step fails or the pipeline pauses
+	StatusNotRun Status = "not_run"
+)
+
+// finalStatusPriority is the order in which the step statuses decide the pipeline status
+var finalStatusPriority = []Status{StatusFailed, StatusTimeout, StatusStopped, StatusPaused}
+
+// GetColoredStatus returns the status colored for the terminal
+func GetColoredStatus(status Status) string {
+	s := string(status)
+	switch status {
+	case StatusSuccess:
+		return common.ColorGreen(s)
+	case StatusFailed, StatusTimeout, StatusStopped:
+		return common.ColorRed(s)
+	case StatusPaused:
+		return common.ColorYellow(s)
+	default:
+		return common.ColorGrey(s)
+	}
+}
+
+// getPaddedStatus returns the colored status padded to the width, as the color
+// codes break the padding of fmt
+func getPaddedStatus(status Status, width int) string {
+	return GetColoredStatus(status) + strings.Repeat(" ", max(width-len(status), 0))
+}
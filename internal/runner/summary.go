@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"time"
+)
+
+// RenderStepSummary returns a table of the status and the elapsed time of each
+// step, broken down by the phases that the steps ran
+func RenderStepSummary(result *Result) string {
+	steps := result.GetSteps()
+
+	durations := make([]map[string]time.Duration, len(steps))
+	used := make(map[string]bool)
+	for i, sr := range steps {
+		durations[i] = sr.GetPhaseDurations()
+		for phase := range durations[i] {
+			used[phase] = true
+		}
+	}
+	var phases []string
+	for _, phase := range phaseOrder {
+		if used[phase] {
+			phases = append(phases, phase)
+		}
+	}
+
+	t := table.NewWriter()
+	style := table.StyleLight
+	// keep the durations in the footer readable
+	style.Format.Footer = text.FormatDefault
+	t.SetStyle(style)
+
+	header := table.Row{"Step", "Status", "Duration"}
+	for _, phase := range phases {
+		header = append(header, phase)
+	}
+	t.AppendHeader(header)
+
+	var total time.Duration
+	totals := make(map[string]time.Duration)
+	for i, sr := range steps {
+		duration := ""
+		if !sr.StartTime.IsZero() {
+			duration = formatPhaseDuration(sr.GetDuration())
+			total += sr.GetDuration()
+		}
+		row := table.Row{fmt.Sprintf("%s %s", sr.GetIdxString(), sr.Name), GetColoredStatus(sr.Status), duration}
+		for _, phase := range phases {
+			d, ok := durations[i][phase]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			totals[phase] += d
+			row = append(row, formatPhaseDuration(d))
+		}
+		t.AppendRow(row)
+	}
+
+	// the totals tell whether the time goes into the scripts or into the overhead
+	if len(phases) > 0 {
+		footer := table.Row{"Total", "", formatPhaseDuration(total)}
+		for _, phase := range phases {
+			footer = append(footer, formatPhaseDuration(totals[phase]))
+		}
+		t.AppendFooter(footer)
+	}
+
+	return t.Render()
+}
+
+func formatPhaseDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
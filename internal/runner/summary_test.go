@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderStepSummary(t *testing.T) {
+	result := NewResult("default", nil)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	build := result.AddStep(1, "build", nil)
+	build.Status = StatusSuccess
+	build.StartTime = start
+	build.EndTime = start.Add(70 * time.Second)
+	build.AddPhase(&PhaseResult{Name: PhaseScript, StartTime: start.Add(10 * time.Second), EndTime: start.Add(70 * time.Second)})
+	build.AddPhase(&PhaseResult{Name: PhaseClone, StartTime: start, EndTime: start.Add(10 * time.Second)})
+
+	result.AddStep(2, "deploy", nil).Status = StatusNotRun
+
+	summary := RenderStepSummary(result)
+	lines := strings.Split(summary, "\n")
+	// the phases are in the order they run in a step
+	assert.Regexp(t, `STEP +│ STATUS +│ DURATION +│ CLONE +│ SCRIPT`, lines[1])
+	assert.Regexp(t, `1 build +│ success +│ 1m10s +│ 10s +│ 1m0s`, lines[3])
+	assert.Regexp(t, `2 deploy +│ not_run +│ +│ +│`, lines[4])
+	assert.Regexp(t, `Total +│ +│ 1m10s +│ 10s +│ 1m0s`, lines[6])
+}
@@ -2,12 +2,17 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
 
 type Task func(ctx context.Context) error
 
+// ErrFailFast is the cause of the cancellation of the other tasks of a
+// FailFastParallelTask when one of them fails
+var ErrFailFast = errors.New("a parallel task failed")
+
 func (t Task) Then(next Task) Task {
 	return func(ctx context.Context) error {
 		err := t(ctx)
@@ -61,6 +66,16 @@ func ChainTask(tasks ...Task) Task {
 }
 
 func ParallelTask(size int, tasks ...Task) Task {
+	return parallelTask(size, false, tasks)
+}
+
+// FailFastParallelTask works like ParallelTask, but cancels the other
+// tasks as soon as one of them fails.
+func FailFastParallelTask(size int, tasks ...Task) Task {
+	return parallelTask(size, true, tasks)
+}
+
+func parallelTask(size int, failFast bool, tasks []Task) Task {
 	return func(ctx context.Context) error {
 		count := len(tasks)
 		taskChan := make(chan Task, count)
@@ -70,10 +85,13 @@ func ParallelTask(size int, tasks ...Task) Task {
 			size = count
 		}
 
+		taskCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
 		for i := 0; i < size; i++ {
 			go func(work <-chan Task, errs chan<- error, idx int) {
 				for task := range work {
-					errs <- task(ctx)
+					errs <- task(taskCtx)
 				}
 			}(taskChan, errChan, i)
 		}
@@ -88,6 +106,9 @@ func ParallelTask(size int, tasks ...Task) Task {
 			err := <-errChan
 			if firstErr == nil {
 				firstErr = err
+				if err != nil && failFast {
+					cancel(ErrFailFast)
+				}
 			}
 		}
 
@@ -54,7 +54,7 @@ func NewSaveArtifactsTask(c *docker.Container, sr *StepResult) Task {
 				return err
 			}
 
-			result.Artifacts[id.String()] = pattern
+			result.AddArtifact(sr, id.String(), pattern)
 		}
 		return nil
 	}
@@ -3,6 +3,7 @@ package runner
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
@@ -12,8 +13,10 @@ import (
 	"io"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 //go:embed scripts/get-cache-key.sh
@@ -24,29 +27,52 @@ func NewContainerCreateTask(c *docker.Container, sr *StepResult) Task {
 		logger := GetLogger(ctx)
 		result := GetResult(ctx)
 
+		// validate the size before creating anything that would need to be removed
+		plan := result.Runner.Plan
+		hasDockerService := false
+		servicesMemory := 0
+		for _, service := range plan.GetStepServices(sr.Step) {
+			svc := plan.Definitions.Services[service]
+			if svc == nil {
+				continue
+			}
+			if svc.IsDockerService() {
+				hasDockerService = true
+			}
+			servicesMemory += svc.GetMemory()
+		}
+
+		size := plan.GetStepSize(sr.Step)
+		totalMemory, err := models.GetSizeMemory(size)
+		if err != nil {
+			return err
+		}
+		if servicesMemory > totalMemory-models.MinBuildMemory {
+			return fmt.Errorf(
+				"the services of the step require %d MB memory, only %d MB available for step size %s",
+				servicesMemory, totalMemory-models.MinBuildMemory, size,
+			)
+		}
+		c.Inputs.Memory = totalMemory - servicesMemory
+		logger.Debugf("build container memory limit: %d MB", c.Inputs.Memory)
+
 		netName := fmt.Sprintf("net_%s", c.Inputs.Name)
 		logger.Debugf("creating network %s", netName)
 
-		net := docker.NewNetwork(netName)
+		net := docker.NewNetwork(netName, c.Inputs.Labels)
 		if err := net.Create(ctx); err != nil {
 			return err
 		}
+		result.Runner.addNetwork(net)
+		// the network is removed by the destroy task even if the container is not created
+		c.Network = net
 
 		logger.Debugf("creating build container %s", c.Inputs.Name)
 		var mounts []mount.Mount
 
-		hasDockerService := false
-		for _, service := range sr.Step.Services {
-			svc := result.Runner.Plan.Definitions.Services[service]
-			if svc != nil && svc.IsDockerService() {
-				hasDockerService = true
-				break
-			}
-		}
-
 		if sr.Step.Script.HasPipe() || hasDockerService {
 			vol := &volume.Volume{
-				Name: fmt.Sprintf("vol_bbp-%s-docker", sr.GetIdxString()),
+				Name: fmt.Sprintf("vol_%s-docker", c.Inputs.Name),
 			}
 			c.DockerDaemonVol = vol
 			mounts = append(
@@ -90,14 +116,28 @@ func NewContainerStartTask(c *docker.Container) Task {
 	}
 }
 
-func NewCloneTask(c *docker.Container) Task {
+func NewCloneTask(c *docker.Container, sr *StepResult) Task {
 	return func(ctx context.Context) error {
 		logger := GetLogger(ctx)
+		result := GetResult(ctx)
+		info := result.Runner.Info
+
 		logger.Debugf("prepare workdir %s", c.Inputs.WorkDir)
 		cmd := []string{"sh", "-ce", fmt.Sprintf("mkdir -p %s && sync", c.Inputs.WorkDir)}
 		if err := c.Exec(ctx, "", cmd, nil); err != nil {
 			return err
 		}
+
+		clone := result.Runner.Plan.GetStepClone(sr.Step)
+		if !clone.IsEnabled() {
+			logger.Debug("clone is disabled")
+			return nil
+		}
+
+		if result.Runner.Options.Clean {
+			return cloneCommit(ctx, c, info, clone)
+		}
+
 		logger.Debugf("cloning project code from %s ", c.Inputs.HostDir)
 		excludePatterns := []string{}
 		ignoreFile := path.Join(c.Inputs.HostDir, ".gitignore")
@@ -108,8 +148,68 @@ func NewCloneTask(c *docker.Container) Task {
 			}
 			excludePatterns = strings.Split(string(data), "\n")
 		}
-		return c.CopyToContainer(ctx, c.Inputs.HostDir, c.Inputs.WorkDir, excludePatterns)
+
+		// not a git repository, copy the project as it is
+		if info.CommitID == "" {
+			return c.CopyToContainer(ctx, c.Inputs.HostDir, c.Inputs.WorkDir, excludePatterns)
+		}
+
+		excludePatterns = append(excludePatterns, ".git")
+		if err := c.CopyToContainer(ctx, c.Inputs.HostDir, c.Inputs.WorkDir, excludePatterns); err != nil {
+			return err
+		}
+
+		repoDir, err := createGitRepo(ctx, info, clone, info.BranchName)
+		defer os.RemoveAll(repoDir)
+		if err != nil {
+			return err
+		}
+		return c.CopyToContainer(ctx, repoDir, c.Inputs.WorkDir, []string{})
+	}
+}
+
+// cloneCommit copies a repository checked out at the commit into the workdir,
+// leaving out all the uncommitted and untracked files of the project
+func cloneCommit(ctx context.Context, c *docker.Container, info *ProjectInfo, clone *models.Clone) error {
+	if info.CommitID == "" {
+		return fmt.Errorf("the project is not a git repository: %s", info.Path)
+	}
+	repoDir, err := createGitRepo(ctx, info, clone, "")
+	defer os.RemoveAll(repoDir)
+	if err != nil {
+		return err
+	}
+	if err := common.CheckoutGitRepo(repoDir); err != nil {
+		return err
+	}
+	return c.CopyToContainer(ctx, repoDir, c.Inputs.WorkDir, []string{})
+}
+
+// createGitRepo creates a temporary git repository of the project commit
+// following the clone settings, detached if the branch is empty
+func createGitRepo(ctx context.Context, info *ProjectInfo, clone *models.Clone, branch string) (string, error) {
+	logger := GetLogger(ctx)
+	repoDir, err := os.MkdirTemp("", "bbp-clone-")
+	if err != nil {
+		return "", err
+	}
+
+	logger.Debugf("creating git repository of commit %s with depth %d", info.CommitID, clone.GetDepth())
+	if err := common.CreateGitRepo(info.Path, repoDir, branch, info.CommitID, clone.GetDepth()); err != nil {
+		return repoDir, err
+	}
+	if clone.IsSkipSSLVerify() {
+		if err := common.SetGitConfig(repoDir, "http.sslVerify", "false"); err != nil {
+			return repoDir, err
+		}
+	}
+	if clone.IsLFS() {
+		logger.Debug("fetching lfs objects")
+		if err := common.FetchGitLFS(repoDir, info.Path, info.CommitID); err != nil {
+			logger.Warnf("failed to fetch lfs objects: %s", err)
+		}
 	}
+	return repoDir, nil
 }
 
 func NewScriptTask(c *docker.Container, sr *StepResult, scripts models.StepScript) Task {
@@ -119,7 +219,7 @@ func NewScriptTask(c *docker.Container, sr *StepResult, scripts models.StepScrip
 		if len(scripts) == 0 {
 			logger.Warn("No script to run")
 			sr.Outputs["script"] = "No script to run"
-			sr.Status = "success"
+			sr.SetStatus(StatusSuccess)
 			return nil
 		}
 		logger.Debug("executing script")
@@ -155,18 +255,63 @@ func NewScriptTask(c *docker.Container, sr *StepResult, scripts models.StepScrip
 			}
 		}
 
-		err := NewCmdTask(c, sr, cmd)(ctx)
+		err := NewCmdTask(c, sr, cmd, nil)(ctx)
 
-		if err != nil {
-			sr.Status = "failed"
+		var exitErr *docker.ExitError
+		if errors.As(err, &exitErr) {
+			sr.ExitCode = exitErr.Code
+		} else if err != nil {
+			sr.ExitCode = 1
+		}
+
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			sr.SetStatus(StatusTimeout)
+		} else if err != nil && errors.Is(err, context.Canceled) {
+			sr.SetStatus(StatusStopped)
+		} else if err != nil {
+			sr.SetStatus(StatusFailed)
+			logMemoryExceeded(ctx, c)
 		} else {
-			sr.Status = "success"
+			sr.SetStatus(StatusSuccess)
 		}
 		return err
 	}
 }
 
-func NewCmdTask(c *docker.Container, sr *StepResult, cmd []string) Task {
+// NewAfterScriptTask runs the after-script of the step with the exit code and the status of the script
+func NewAfterScriptTask(c *docker.Container, sr *StepResult) Task {
+	return func(ctx context.Context) error {
+		// the build container is not created if the step fails before
+		if len(sr.Step.AfterScript) == 0 || c.ID == "" {
+			return nil
+		}
+		logger := GetLogger(ctx)
+		status, exitCode := getAfterScriptStatus(sr)
+		logger.Debugf("executing after-script, step status: %s, exit code: %d", status, exitCode)
+		envs := map[string]string{
+			"BITBUCKET_EXIT_CODE":   strconv.Itoa(exitCode),
+			"BITBUCKET_STEP_STATUS": string(status),
+		}
+		return NewCmdTask(c, sr, sr.Step.AfterScript, envs)(ctx)
+	}
+}
+
+// getAfterScriptStatus returns the status and the exit code of the step for the
+// after-script. The step is still pending when it fails before the script, e.g.
+// in the clone, and the exit code is only 0 for a successful step.
+func getAfterScriptStatus(sr *StepResult) (Status, int) {
+	status := sr.GetStatus()
+	if status == StatusPending {
+		status = StatusFailed
+	}
+	exitCode := sr.ExitCode
+	if status != StatusSuccess && exitCode == 0 {
+		exitCode = 1
+	}
+	return status, exitCode
+}
+
+func NewCmdTask(c *docker.Container, sr *StepResult, cmd []string, envs map[string]string) Task {
 	return func(ctx context.Context) error {
 		result := GetResult(ctx)
 
@@ -174,38 +319,80 @@ func NewCmdTask(c *docker.Container, sr *StepResult, cmd []string) Task {
 			return nil
 		}
 
-		var newCmd []string
-		for _, c := range cmd {
-			newCmd = append(newCmd, fmt.Sprintf("echo '+ %s'", c), c, "echo '\n'")
-		}
-
-		cmd = []string{"sh", "-ce", strings.Join(newCmd, "\n")}
-		return c.Exec(ctx, c.Inputs.WorkDir, cmd, func(reader io.Reader) error {
-			logPath := fmt.Sprintf("%s/logs/%s-%s.log", result.GetResultPath(), sr.GetIdxString(), sr.Name)
-			file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		var cw *commandWriter
+		script := []string{"sh", "-ce", fmt.Sprintf("echo $$ > %s\n", scriptPidFile) + newCommandScript(cmd)}
+		err := c.ExecWithEnv(ctx, c.Inputs.WorkDir, script, envs, func(reader io.Reader) error {
+			file, err := os.OpenFile(sr.GetLogPath(), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 			if err != nil {
 				return err
 			}
 			defer file.Close()
 
-			if _, err := io.Copy(file, reader); err != nil {
+			fw := newTimestampWriter(file)
+			defer fw.Flush()
+
+			var out io.Writer = fw
+			if result.Runner.Options.Output == OutputStream {
+				prefix := fmt.Sprintf("%s[%s][%s] ", common.ColorGrey(fmt.Sprintf("[%s]", result.ID)), sr.GetIdxString(), sr.Name)
+				pw := newPrefixWriter(os.Stdout, prefix)
+				defer pw.Flush()
+				out = io.MultiWriter(fw, pw)
+			}
+
+			cw = newCommandWriter(out, sr, cmd)
+			defer cw.Flush()
+
+			if _, err := io.Copy(cw, reader); err != nil {
 				return err
 			}
 			return nil
 		})
+		if cw != nil {
+			cw.Close(err)
+		}
+		if ctx.Err() != nil {
+			killScript(ctx, c)
+		}
+		return err
+	}
+}
+
+// scriptPidFile holds the pid of the script running in the build container
+const scriptPidFile = "/tmp/bbp-script.pid"
+
+// killScript kills the script and its commands, as cancelling the context only
+// stops the output of the exec. Otherwise the script would keep running with
+// the after-script.
+func killScript(ctx context.Context, c *docker.Container) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	// the exec runs with a TTY, so the script leads the process group of its commands
+	cmd := fmt.Sprintf(`pid=$(cat %[1]s) && rm -f %[1]s && (kill -KILL -- -"$pid" 2>/dev/null || kill -KILL "$pid")`, scriptPidFile)
+	if err := c.Exec(ctx, "", []string{"sh", "-c", cmd}, nil); err != nil {
+		GetLogger(ctx).Debugf("failed to kill the script: %s", err)
+	}
+}
+
+// logMemoryExceeded reports the containers of the step killed for running out of memory
+func logMemoryExceeded(ctx context.Context, c *docker.Container) {
+	logger := GetLogger(ctx)
+	for _, sc := range c.Network.Containers {
+		oom, err := sc.IsOOMKilled(context.Background())
+		if err == nil && oom {
+			logger.Errorf("Container '%s' exceeded memory limit.", sc.Inputs.NetworkAlias)
+		}
 	}
 }
 
 func NewContainerDestroyTask(c *docker.Container) Task {
 	return func(ctx context.Context) error {
-
-		if c.ID == "" {
+		// the network is not created if the step fails before
+		if c.Network == nil {
 			return nil
 		}
 		logger := GetLogger(ctx)
 		logger.Debugf("destroying network and containers %s", c.Inputs.Name)
-		// use a new context to avoid canceling the parent context
-		ctx = context.Background()
+		GetResult(ctx).Runner.removeNetwork(c.Network)
 		return c.Network.Destroy(ctx)
 	}
 }
@@ -3,6 +3,8 @@ package runner
 import (
 	"context"
 	"github.com/zhex/local-bbp/internal/docker"
+	"io"
+	"os"
 )
 
 func NewImagePullTask(c *docker.Container) Task {
@@ -16,6 +18,11 @@ func NewImagePullTask(c *docker.Container) Task {
 			return nil
 		}
 		log.Debugf("pulling image %s", c.Inputs.Image.Name)
-		return c.Pull(ctx)
+		var out io.Writer = os.Stdout
+		opts := GetResult(ctx).Runner.Options
+		if opts.TUI || opts.Output == OutputQuiet {
+			out = io.Discard
+		}
+		return c.Pull(ctx, out)
 	}
 }
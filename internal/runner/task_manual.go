@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zhex/local-bbp/internal/common"
+	"strings"
+	"time"
+)
+
+var ErrPipelinePaused = errors.New("pipeline paused at manual step")
+var ErrPipelineStopped = errors.New("pipeline stopped")
+
+func WithManualTrigger(task Task, sr *StepResult) Task {
+	return func(ctx context.Context) error {
+		logger := GetLogger(ctx)
+		opts := sr.Result.Runner.Options
+
+		// a step left out of the run needs no approval
+		if sr.GetStatus() == StatusSkipped {
+			return task(ctx)
+		}
+
+		if opts.AutoApprove {
+			logger.Infof("Manual step approved automatically: %s", sr.Name)
+			return task(ctx)
+		}
+
+		sr.SetStatus(StatusPaused)
+		if opts.StopAtManual {
+			logger.Infof("Pipeline paused at manual step: %s", sr.Name)
+			return ErrPipelinePaused
+		}
+
+		question := fmt.Sprintf("Run manual step [%s] %s? [y]es / [s]kip / [a]bort: ", sr.GetIdxString(), sr.Name)
+		for {
+			answer, err := common.Prompt(ctx, question)
+			if ctx.Err() != nil {
+				sr.SetStatus(StatusStopped)
+				return ctx.Err()
+			}
+			if err != nil {
+				logger.Infof("Pipeline paused at manual step: %s", sr.Name)
+				return ErrPipelinePaused
+			}
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+				sr.SetStatus(StatusPending)
+				sr.SetStartTime(time.Now())
+				return task(ctx)
+			case "s", "skip":
+				sr.SetStatus(StatusSkipped)
+				logger.Infof("Manual step skipped: %s", sr.Name)
+				return nil
+			case "a", "abort":
+				sr.SetStatus(StatusStopped)
+				logger.Infof("Pipeline stopped at manual step: %s", sr.Name)
+				return ErrPipelineStopped
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/zhex/local-bbp/internal/common"
 	"github.com/zhex/local-bbp/internal/docker"
+	"github.com/zhex/local-bbp/internal/models"
 )
 
 func NewCreateServicesTask(c *docker.Container, sr *StepResult) Task {
@@ -13,23 +14,29 @@ func NewCreateServicesTask(c *docker.Container, sr *StepResult) Task {
 		logger := GetLogger(ctx)
 		result := GetResult(ctx)
 
-		if len(sr.Step.Services) == 0 {
+		services := result.Runner.Plan.GetStepServices(sr.Step)
+		if len(services) == 0 {
 			return nil
 		}
 
 		fu := NewFieldUpdater(c.Inputs.Envs)
-		for _, service := range sr.Step.Services {
+		for _, service := range services {
 			logger.Debugf("creating service: %s", service)
 			svc := result.Runner.Plan.Definitions.Services[service]
 			if svc == nil {
 				return fmt.Errorf("service not found: %s", service)
 			}
+			if svc.GetMemory() < models.MinServiceMemory {
+				return fmt.Errorf("service %s requires at least %d MB memory", service, models.MinServiceMemory)
+			}
 
 			inputs := &docker.Input{
-				Name:         fmt.Sprintf("bbp-%s-%s", sr.GetIdxString(), service),
+				Name:         fmt.Sprintf("%s-%s", c.Inputs.Name, service),
 				NetworkAlias: service,
 				Image:        svc.Image,
 				Envs:         common.MergeMaps(fu.UpdateMap(svc.Variables), c.Inputs.Envs),
+				Memory:       svc.GetMemory(),
+				Labels:       c.Inputs.Labels,
 			}
 
 			var mounts []mount.Mount
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/zhex/local-bbp/internal/docker"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// shellLock lets one interactive shell use the terminal at a time
+var shellLock sync.Mutex
+
+// shellCmd starts bash if the image has it, sh otherwise
+var shellCmd = []string{"sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+// NewShellTask opens an interactive shell in the workdir of the build container,
+// the task ends when the shell exits
+func NewShellTask(c *docker.Container, envs map[string]string) Task {
+	return func(ctx context.Context) error {
+		shellLock.Lock()
+		defer shellLock.Unlock()
+
+		// the shell is closed by the user, not by the step timeout or the interrupt
+		err := c.ExecInteractive(context.WithoutCancel(ctx), c.Inputs.WorkDir, shellCmd, envs, os.Stdin, os.Stdout)
+
+		// the exit code of the last command in the shell is not an error of the step
+		var exitErr *docker.ExitError
+		if errors.As(err, &exitErr) {
+			return nil
+		}
+		return err
+	}
+}
+
+// NewDebugShellTask opens an interactive shell in the build container when the
+// script of the step fails, the container is kept until the shell exits
+func NewDebugShellTask(c *docker.Container, sr *StepResult) Task {
+	return func(ctx context.Context) error {
+		if sr.GetStatus() != StatusFailed || c.ID == "" {
+			return nil
+		}
+		logger := GetLogger(ctx)
+		logger.Infof("Step failed, opening a debug shell in the build container. Exit the shell to continue")
+		envs := map[string]string{
+			"BITBUCKET_EXIT_CODE": strconv.Itoa(sr.ExitCode),
+		}
+		return NewShellTask(c, envs)(ctx)
+	}
+}
+
+// NewExecShellTask opens an interactive shell in the build container in place of
+// the script of the step
+func NewExecShellTask(c *docker.Container, sr *StepResult) Task {
+	return func(ctx context.Context) error {
+		logger := GetLogger(ctx)
+		logger.Infof("Opening a shell in the build container. Exit the shell to clean up")
+		if err := NewShellTask(c, nil)(ctx); err != nil {
+			sr.SetStatus(StatusFailed)
+			return err
+		}
+		sr.SetStatus(StatusSuccess)
+		return nil
+	}
+}
@@ -146,3 +146,86 @@ func TestWithTimeout(t *testing.T) {
 	_ = chain(context.Background())
 	assert.Equal(t, "3", str)
 }
+
+func TestFailFastParallelTask(t *testing.T) {
+	ctx := context.Background()
+	var task1 Task = func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("failed")
+	}
+	var task2 Task = func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+	var cause error
+	var task3 Task = func(ctx context.Context) error {
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return ctx.Err()
+	}
+	start := time.Now()
+	err := FailFastParallelTask(3, task1, task2, task3)(ctx)
+	assert.EqualError(t, err, "failed")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.ErrorIs(t, cause, ErrFailFast)
+
+	start = time.Now()
+	err = ParallelTask(2, task1, task2)(ctx)
+	assert.EqualError(t, err, "failed")
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestWithGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var task Task = func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			return nil
+		}
+	}
+	assert.NoError(t, WithGracePeriod(task, 100*time.Millisecond)(ctx))
+
+	var longTask Task = func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+	assert.ErrorIs(t, WithGracePeriod(longTask, 50*time.Millisecond)(ctx), context.Canceled)
+}
+
+func TestGetAfterScriptStatus(t *testing.T) {
+	sr := NewResult("default", nil).AddStep(1, "build", nil)
+
+	// the step fails before the script, e.g. in the clone
+	status, exitCode := getAfterScriptStatus(sr)
+	assert.Equal(t, StatusFailed, status)
+	assert.Equal(t, 1, exitCode)
+
+	sr.Status = StatusFailed
+	sr.ExitCode = 2
+	status, exitCode = getAfterScriptStatus(sr)
+	assert.Equal(t, StatusFailed, status)
+	assert.Equal(t, 2, exitCode)
+
+	sr.Status = StatusTimeout
+	sr.ExitCode = 0
+	status, exitCode = getAfterScriptStatus(sr)
+	assert.Equal(t, StatusTimeout, status)
+	assert.Equal(t, 1, exitCode)
+
+	sr.Status = StatusSuccess
+	status, exitCode = getAfterScriptStatus(sr)
+	assert.Equal(t, StatusSuccess, status)
+	assert.Equal(t, 0, exitCode)
+}
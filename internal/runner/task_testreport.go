@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/zhex/local-bbp/internal/common"
+	"github.com/zhex/local-bbp/internal/docker"
+	"github.com/zhex/local-bbp/internal/testreport"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// NewTestReportsTask copies the test reports out of the build container after the
+// script, parses them and records the summary on the step. It does not fail the step.
+func NewTestReportsTask(c *docker.Container, sr *StepResult) Task {
+	return func(ctx context.Context) error {
+		// the build container is not created if the step fails before
+		if c.ID == "" {
+			return nil
+		}
+		logger := GetLogger(ctx)
+		result := GetResult(ctx)
+
+		dirs, err := findTestReportDirs(ctx, c)
+		if err != nil {
+			logger.Warnf("Error finding test reports: %s", err)
+			return nil
+		}
+		if len(dirs) == 0 {
+			return nil
+		}
+
+		target := path.Join(result.GetResultPath(), "test-reports", sr.GetIdxString())
+		for i, dir := range dirs {
+			logger.Debugf("copying test reports: %s", dir)
+			// the directories can have the same name
+			if err := c.CopyToHost(ctx, dir, path.Join(target, strconv.Itoa(i))); err != nil {
+				logger.Warnf("Error copying test reports %s: %s", dir, err)
+				return nil
+			}
+		}
+
+		summary, err := testreport.ParseDir(target)
+		if err != nil {
+			logger.Warnf("Error parsing test reports: %s", err)
+			return nil
+		}
+		if summary.Total == 0 {
+			return nil
+		}
+		sr.Tests = summary
+
+		logger.Infof("Tests: %d total, %d passed, %d failed, %d skipped", summary.Total, summary.Passed, summary.Failed, summary.Skipped)
+		for _, failure := range summary.Failures {
+			logger.Infof("%s %s: %s", common.ColorRed("Failed test"), failure.FullName(), failure.Message)
+		}
+		return nil
+	}
+}
+
+// findTestReportDirs returns the test report directories in the workdir of the container
+func findTestReportDirs(ctx context.Context, c *docker.Container) ([]string, error) {
+	var names []string
+	for _, name := range testreport.ReportDirs {
+		names = append(names, fmt.Sprintf("-name '%s'", name))
+	}
+	cmd := fmt.Sprintf("find . -maxdepth %d -type d \\( %s \\) -prune", testreport.MaxDepth, strings.Join(names, " -o "))
+
+	var out bytes.Buffer
+	err := c.Exec(ctx, c.Inputs.WorkDir, []string{"sh", "-c", cmd}, func(reader io.Reader) error {
+		_, err := io.Copy(&out, reader)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "." {
+			continue
+		}
+		dirs = append(dirs, strings.TrimPrefix(line, "./"))
+	}
+	return dirs, nil
+}
@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/zhex/local-bbp/internal/common"
+	"github.com/zhex/local-bbp/internal/models"
+	"strings"
+)
+
+// resolveVariables validates the variables given for the pipeline and asks
+// for the values of the declared variables which are not given, until the
+// context is done
+func (r *Runner) resolveVariables(ctx context.Context, name string) (map[string]string, error) {
+	declared := r.Plan.GetPipelineVariables(name)
+	for key := range r.Options.Variables {
+		found := false
+		for _, v := range declared {
+			if v.Name == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("variable %s is not declared by the pipeline %s", key, name)
+		}
+	}
+
+	values := make(map[string]string)
+	for _, v := range declared {
+		value, ok := r.Options.Variables[v.Name]
+		if !ok {
+			var err error
+			if value, err = promptVariable(ctx, v); err != nil {
+				return nil, err
+			}
+		}
+		if err := v.Validate(value); err != nil {
+			return nil, err
+		}
+		values[v.Name] = value
+	}
+	return values, nil
+}
+
+func promptVariable(ctx context.Context, v *models.Variable) (string, error) {
+	question := v.Name
+	if v.Description != "" {
+		question += fmt.Sprintf(" (%s)", v.Description)
+	}
+	if len(v.AllowedValues) > 0 {
+		question += fmt.Sprintf(" [%s]", strings.Join(v.AllowedValues, "/"))
+	}
+	if v.Default != "" {
+		question += fmt.Sprintf(" default: %s", v.Default)
+	}
+
+	for {
+		answer, err := common.Prompt(ctx, question+": ")
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if err != nil {
+			if v.Default != "" {
+				return v.Default, nil
+			}
+			return "", fmt.Errorf("missing value for variable %s", v.Name)
+		}
+		if answer == "" {
+			answer = v.Default
+		}
+		if err := v.Validate(answer); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return answer, nil
+	}
+}
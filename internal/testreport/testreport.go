@@ -0,0 +1,206 @@
+package testreport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReportDirs are the names of the directories that the test reports are picked up from
+var ReportDirs = []string{"test-results", "test-reports", "surefire-reports", "failsafe-reports", "TestResults"}
+
+// MaxDepth is how deep the report directories are searched for in the clone directory
+const MaxDepth = 4
+
+type Summary struct {
+	Total    int        `json:"total"`
+	Passed   int        `json:"passed"`
+	Failed   int        `json:"failed"`
+	Skipped  int        `json:"skipped"`
+	Failures []*Failure `json:"failures,omitempty"`
+}
+
+type Failure struct {
+	ClassName string `json:"className,omitempty"`
+	Name      string `json:"name"`
+	Message   string `json:"message,omitempty"`
+	Details   string `json:"details,omitempty"`
+}
+
+// FullName returns the name of the test prefixed with its class name
+func (f *Failure) FullName() string {
+	if f.ClassName == "" {
+		return f.Name
+	}
+	return f.ClassName + "." + f.Name
+}
+
+func (s *Summary) add(other *Summary) {
+	s.Total += other.Total
+	s.Passed += other.Passed
+	s.Failed += other.Failed
+	s.Skipped += other.Skipped
+	s.Failures = append(s.Failures, other.Failures...)
+}
+
+// ParseDir parses all the xml reports in the directory, the files that are not
+// test reports are ignored
+func ParseDir(dir string) (*Summary, error) {
+	summary := &Summary{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".xml") {
+			return nil
+		}
+		s, err := ParseFile(p)
+		if err != nil {
+			return nil
+		}
+		summary.add(s)
+		return nil
+	})
+	return summary, err
+}
+
+// ParseFile parses the JUnit or xUnit report
+func ParseFile(path string) (*Summary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse parses the JUnit or xUnit report, the format is detected by the root element
+func Parse(r io.Reader) (*Summary, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "testsuites", "testsuite":
+			var suite junitSuite
+			if err := decoder.DecodeElement(&suite, &start); err != nil {
+				return nil, err
+			}
+			return suite.summary(), nil
+		case "assemblies", "assembly":
+			var assembly xunitAssembly
+			if err := decoder.DecodeElement(&assembly, &start); err != nil {
+				return nil, err
+			}
+			return assembly.summary(), nil
+		default:
+			return nil, fmt.Errorf("unknown test report element: %s", start.Name.Local)
+		}
+	}
+}
+
+// junitSuite is a testsuites or testsuite element, which can be nested
+type junitSuite struct {
+	Suites []*junitSuite `xml:"testsuite"`
+	Cases  []*junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	Failure   *junitResult `xml:"failure"`
+	Error     *junitResult `xml:"error"`
+	Skipped   *junitResult `xml:"skipped"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (s *junitSuite) summary() *Summary {
+	summary := &Summary{}
+	for _, suite := range s.Suites {
+		summary.add(suite.summary())
+	}
+	for _, tc := range s.Cases {
+		summary.Total++
+		result := tc.Failure
+		if result == nil {
+			result = tc.Error
+		}
+		switch {
+		case result != nil:
+			summary.Failed++
+			summary.Failures = append(summary.Failures, &Failure{
+				ClassName: tc.ClassName,
+				Name:      tc.Name,
+				Message:   result.Message,
+				Details:   strings.TrimSpace(result.Text),
+			})
+		case tc.Skipped != nil:
+			summary.Skipped++
+		default:
+			summary.Passed++
+		}
+	}
+	return summary
+}
+
+// xunitAssembly is an assemblies or assembly element of the xUnit v2 format
+type xunitAssembly struct {
+	Assemblies  []*xunitAssembly   `xml:"assembly"`
+	Collections []*xunitCollection `xml:"collection"`
+}
+
+type xunitCollection struct {
+	Tests []*xunitTest `xml:"test"`
+}
+
+type xunitTest struct {
+	Name    string `xml:"name,attr"`
+	Type    string `xml:"type,attr"`
+	Method  string `xml:"method,attr"`
+	Result  string `xml:"result,attr"`
+	Failure *struct {
+		Message    string `xml:"message"`
+		StackTrace string `xml:"stack-trace"`
+	} `xml:"failure"`
+}
+
+func (a *xunitAssembly) summary() *Summary {
+	summary := &Summary{}
+	for _, assembly := range a.Assemblies {
+		summary.add(assembly.summary())
+	}
+	for _, collection := range a.Collections {
+		for _, test := range collection.Tests {
+			summary.Total++
+			switch test.Result {
+			case "Pass":
+				summary.Passed++
+			case "Skip":
+				summary.Skipped++
+			default:
+				summary.Failed++
+				failure := &Failure{Name: test.Name}
+				if test.Failure != nil {
+					failure.Message = strings.TrimSpace(test.Failure.Message)
+					failure.Details = strings.TrimSpace(test.Failure.StackTrace)
+				}
+				summary.Failures = append(summary.Failures, failure)
+			}
+		}
+	}
+	return summary
+}
@@ -0,0 +1,49 @@
+package testreport
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseFile_JUnit(t *testing.T) {
+	summary, err := ParseFile("testdata/junit.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, summary.Total)
+	assert.Equal(t, 1, summary.Passed)
+	assert.Equal(t, 2, summary.Failed)
+	assert.Equal(t, 1, summary.Skipped)
+	assert.Equal(t, "math.divides", summary.Failures[0].FullName())
+	assert.Equal(t, "expected 2 to equal 3", summary.Failures[0].Message)
+	assert.Equal(t, "AssertionError: expected 2 to equal 3\n    at divide.test.js:10:5", summary.Failures[0].Details)
+	assert.Equal(t, "TypeError: undefined is not a function", summary.Failures[1].Message)
+}
+
+func TestParseFile_XUnit(t *testing.T) {
+	summary, err := ParseFile("testdata/xunit.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, &Summary{
+		Total:   3,
+		Passed:  1,
+		Failed:  1,
+		Skipped: 1,
+		Failures: []*Failure{{
+			Name:    "Example.Tests.CalculatorTests.Divide",
+			Message: "Assert.Equal() Failure",
+			Details: "at Example.Tests.CalculatorTests.Divide()",
+		}},
+	}, summary)
+}
+
+func TestParseFile_Unknown(t *testing.T) {
+	_, err := ParseFile("testdata/pom.xml")
+	assert.Error(t, err)
+}
+
+func TestParseDir(t *testing.T) {
+	summary, err := ParseDir("testdata")
+	assert.NoError(t, err)
+	assert.Equal(t, 9, summary.Total)
+	assert.Equal(t, 4, summary.Passed)
+	assert.Equal(t, 3, summary.Failed)
+	assert.Equal(t, 2, summary.Skipped)
+}